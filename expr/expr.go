@@ -29,6 +29,28 @@ const (
 	Contains    Op = "$contains"
 	NotContains Op = "$not_contains"
 
+	In  Op = "$in"
+	Nin Op = "$nin"
+
+	// Exists and NotExists are marshaled as $exists true and false.
+	Exists    Op = "$exists"
+	NotExists Op = "$not_exists"
+
+	// ElemMatch matches the array field, which has at least one element
+	// satisfying the condition in the List.
+	ElemMatch    Op = "$elemMatch"
+	NotElemMatch Op = "$not_elemMatch"
+
+	Regex    Op = "$regex"
+	NotRegex Op = "$not_regex"
+
+	// Prefix and suffix matches are marshaled as $regex
+	// with escaped and anchored pattern.
+	Prefix    Op = "$prefix"
+	NotPrefix Op = "$not_prefix"
+	Suffix    Op = "$suffix"
+	NotSuffix Op = "$not_suffix"
+
 	TrueOp  Op = "$true"
 	FalseOp Op = "$false"
 
@@ -84,6 +106,10 @@ func NewExpr(tp Op, x Operand, y Operand) Expr {
 	return Expr{Type: tp, X: x, Y: y}
 }
 
+func NewElemMatch(field Operand, cond Expr) Expr {
+	return Expr{Type: ElemMatch, X: field, List: []Expr{cond}}
+}
+
 func NewUpdIfExpr(tp Op, cond Expr, body []Expr) Expr {
 	return Expr{Type: tp, ListClient: []Expr{cond}, List: body}
 }
@@ -185,6 +211,8 @@ func simplifyOr(ops ...Expr) ([]Expr, []Expr) {
 			if len(v.ListClient) > 0 {
 				resClient = append(resClient, v.ListClient...)
 			}
+		case v.Type == AndOp && len(v.List) == 0:
+			resClient = append(resClient, v)
 		default:
 			if v.ClientEval {
 				resClient = append(resClient, v)
@@ -283,6 +311,30 @@ func Negate(e Expr) Expr {
 		e.Type = NotContains
 	case NotContains:
 		e.Type = Contains
+	case ElemMatch:
+		e.Type = NotElemMatch
+	case NotElemMatch:
+		e.Type = ElemMatch
+	case Exists:
+		e.Type = NotExists
+	case NotExists:
+		e.Type = Exists
+	case In:
+		e.Type = Nin
+	case Nin:
+		e.Type = In
+	case Regex:
+		e.Type = NotRegex
+	case NotRegex:
+		e.Type = Regex
+	case Prefix:
+		e.Type = NotPrefix
+	case NotPrefix:
+		e.Type = Prefix
+	case Suffix:
+		e.Type = NotSuffix
+	case NotSuffix:
+		e.Type = Suffix
 	case TrueOp:
 		panic("true is not expected")
 	case FalseOp:
@@ -15,11 +15,11 @@
 package expr
 
 import (
-	"go/ast"
-
-	"github.com/tigrisdata/tigrisgen/util"
+	"errors"
 )
 
+var ErrInvalidOperands = errors.New("field name, arg, func call or constant expected in binary operation")
+
 type OperandType int
 
 const (
@@ -34,6 +34,13 @@ type Operand struct {
 	Type   OperandType
 	Value  any
 	Value1 any
+
+	// Captured is the name of the variable captured by the closure,
+	// when the Arg operand refers to it instead of the query arguments.
+	Captured string
+	// Pipeline is the template pipeline computing the Arg operand value,
+	// used instead of the path when set.
+	Pipeline string
 }
 
 func NewOperand(val any, typ OperandType) Operand {
@@ -52,21 +59,47 @@ func NewArg(val any) Operand {
 	return Operand{Type: Arg, Value: val}
 }
 
+func NewCaptured(name string, path string) Operand {
+	return Operand{Type: Arg, Value: path, Captured: name}
+}
+
+// ArgPath returns the template path of the Arg operand value.
+// Query arguments are available in the template as .Arg and
+// variables captured by the closure are looked up by the captured
+// template function, which fails if the client didn't supply them.
+func (o Operand) ArgPath() string {
+	if o.Pipeline != "" {
+		return "(" + o.Pipeline + ")"
+	}
+
+	p := ".Arg"
+	if o.Captured != "" {
+		p = `(captured $ "` + o.Captured + `")`
+	}
+
+	if s, _ := o.Value.(string); s != "" {
+		p += "." + s
+	}
+
+	return p
+}
+
+func NewPipeline(pipeline string) Operand {
+	return Operand{Type: Arg, Pipeline: pipeline}
+}
+
 func NewFunc(arg any, arg1 any) Operand {
 	return Operand{Type: Func, Value: arg, Value1: arg1}
 }
 
-func ValidateOperands(x Operand, y Operand, e *ast.BinaryExpr) {
+func ValidateOperands(x Operand, y Operand) error {
 	if x.Type == Constant && y.Type == Constant ||
 		x.Type == Field && y.Type == Field ||
-		x.Type == Arg && y.Type == Arg ||
 		x.Type == Func && y.Type == Func ||
 		x.Type == Func && y.Type != Constant ||
 		y.Type == Func && x.Type != Constant {
-		if e != nil {
-			util.Fatal("field name, arg, func call or constant expected in binary operation, got: %v %v %v", e.X, e.Op, e.Y)
-		}
-
-		util.Fatal("field name, arg, func call or constant expected in binary operation")
+		return ErrInvalidOperands
 	}
+
+	return nil
 }
@@ -0,0 +1,154 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"github.com/tigrisdata/tigrisgen/expr"
+)
+
+// argFuncs are the functions, which are allowed to be applied to the arguments.
+// The values are the names of the functions in the template FuncMap.
+var argFuncs = map[string]string{
+	"strings.ToLower":    "lower",
+	"strings.ToUpper":    "upper",
+	"strings.TrimSpace":  "trimSpace",
+	"strings.Trim":       "trim",
+	"strings.TrimPrefix": "trimPrefix",
+	"strings.TrimSuffix": "trimSuffix",
+	"strings.ReplaceAll": "replaceAll",
+	"strconv.Itoa":       "itoa",
+}
+
+// argConversions are the template functions, which convert
+// the arguments to the basic types of the function parameters.
+var argConversions = map[types.BasicKind]string{
+	types.String:  "str",
+	types.Int:     "int",
+	types.Float64: "float",
+}
+
+// argMethods are the methods, which are allowed to be called on the arguments.
+// The methods are called by the template directly.
+var argMethods = map[string]bool{
+	"(github.com/google/uuid.UUID).String": true,
+	"(time.Time).UTC":                      true,
+	"(time.Time).Unix":                     true,
+	"(time.Time).UnixMilli":                true,
+	"(time.Time).Truncate":                 true,
+	"(time.Time).Format":                   true,
+}
+
+// argCallFunc returns the allow-listed function or method called by the expression.
+func (f *funcParser) argCallFunc(e *ast.CallExpr) (*types.Func, bool) {
+	var id *ast.Ident
+
+	switch fn := unparen(e.Fun).(type) {
+	case *ast.Ident:
+		id = fn
+	case *ast.SelectorExpr:
+		id = fn.Sel
+	default:
+		return nil, false
+	}
+
+	fn, ok := f.pi.TypesInfo.Uses[id].(*types.Func)
+	if !ok {
+		return nil, false
+	}
+
+	if _, ok := argFuncs[objectKey(fn)]; ok {
+		return fn, true
+	}
+
+	return fn, argMethods[fn.FullName()]
+}
+
+// parseArgCall translates the allow-listed function or method call
+// on the arguments, like strings.ToLower(args.Email) or args.ID.String(),
+// to the template pipeline evaluated when the template is rendered.
+func (f *funcParser) parseArgCall(e *ast.CallExpr) (expr.Operand, bool) {
+	fn, ok := f.argCallFunc(e)
+	if !ok {
+		return expr.Operand{}, false
+	}
+
+	var args []string
+
+	if name, ok := argFuncs[objectKey(fn)]; ok {
+		args = append(args, name)
+	} else {
+		x := unparen(e.Fun).(*ast.SelectorExpr).X
+
+		recv := f.parseOperand(x)
+		if recv.Type != expr.Arg {
+			errorf(f.pi, e, "method call is only supported on arguments")
+		}
+
+		if !types.Identical(f.valueType(x), f.pi.TypesInfo.TypeOf(x)) {
+			errorf(f.pi, x, "conversion of the %v receiver is not supported", fn.Name())
+		}
+
+		args = append(args, recv.ArgPath()+"."+fn.Name())
+	}
+
+	params := fn.Type().(*types.Signature).Params()
+
+	for i, a := range e.Args {
+		x := f.parseOperand(a)
+		if x.Type != expr.Arg && x.Type != expr.Constant {
+			errorf(f.pi, a, "only arguments and constants are supported in the %v call", fn.Name())
+		}
+
+		v := f.tmplValue(x, a)
+		if x.Type == expr.Arg {
+			v = f.convertArg(a, params.At(i).Type(), v)
+		}
+
+		args = append(args, v)
+	}
+
+	return expr.NewPipeline(strings.Join(args, " ")), true
+}
+
+// convertArg converts the template value of the argument to the type
+// of the function parameter. Conversions are transparent for the operands,
+// so the template would pass the value of the original type otherwise.
+// The type of the arithmetic result is converted as well, as it's
+// not tracked by the template.
+func (f *funcParser) convertArg(a ast.Expr, param types.Type, v string) string {
+	from := f.valueType(a)
+
+	switch unparen(stripConversions(f.pi, a)).(type) {
+	case *ast.BinaryExpr, *ast.UnaryExpr:
+	default:
+		if types.Identical(from, param) {
+			return v
+		}
+	}
+
+	if b, ok := param.(*types.Basic); ok {
+		if name, ok := argConversions[b.Kind()]; ok {
+			return "(" + name + " " + v + ")"
+		}
+	}
+
+	errorf(f.pi, a, "conversion from %v to %v is not supported in the function call", from, param)
+
+	return v
+}
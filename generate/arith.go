@@ -0,0 +1,66 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"go/ast"
+	"go/token"
+
+	"github.com/tigrisdata/tigrisgen/expr"
+)
+
+// arithHelpers are the template functions evaluating the arithmetic operators.
+var arithHelpers = map[token.Token]string{
+	token.ADD: "add",
+	token.SUB: "sub",
+	token.MUL: "mul",
+	token.QUO: "div",
+	token.REM: "mod",
+}
+
+// parseArithOperand translates the arithmetic expression or string
+// concatenation of the arguments and constants, like args.Base*2,
+// to the template pipeline evaluated when the template is rendered.
+// Constant expressions are evaluated by the type checker.
+func (f *funcParser) parseArithOperand(e ast.Expr) (expr.Operand, bool) {
+	switch ee := e.(type) {
+	case *ast.BinaryExpr:
+		helper, ok := arithHelpers[ee.Op]
+		if !ok {
+			return expr.Operand{}, false
+		}
+
+		return expr.NewPipeline(helper + " " + f.arithValue(ee.X) + " " + f.arithValue(ee.Y)), true
+	case *ast.UnaryExpr:
+		switch ee.Op {
+		case token.ADD:
+			return f.parseOperand(ee.X), true
+		case token.SUB:
+			return expr.NewPipeline("sub 0 " + f.arithValue(ee.X)), true
+		}
+	}
+
+	return expr.Operand{}, false
+}
+
+// arithValue returns the template value of the operand of the arithmetic expression.
+func (f *funcParser) arithValue(e ast.Expr) string {
+	x := f.parseOperand(e)
+	if x.Type != expr.Arg && x.Type != expr.Constant {
+		errorf(f.pi, e, "only arguments and constants are supported in arithmetic expressions")
+	}
+
+	return f.tmplValue(x, e)
+}
@@ -0,0 +1,379 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// assignIndex maps variables and struct fields to the expressions
+// assigned to them in the package.
+// The index is flow-insensitive: the assignments are not ordered, so
+// a variable assigned more than once resolves to every value assigned
+// to it anywhere in the package, including the overwritten ones.
+type assignIndex struct {
+	// nil value means that the variable is assigned
+	// the value which can't be tracked.
+	values map[*types.Var][]ast.Expr
+	params map[*types.Var]bool
+}
+
+func (g *generator) assignIndex(pi *packages.Package) *assignIndex {
+	if idx, ok := g.assigns[pi]; ok {
+		return idx
+	}
+
+	idx := buildAssignIndex(pi)
+
+	g.assigns[pi] = idx
+
+	return idx
+}
+
+// exprVar returns variable or struct field the expression refers to.
+func exprVar(pi *packages.Package, e ast.Expr) *types.Var {
+	switch l := unparen(e).(type) {
+	case *ast.Ident:
+		if v, ok := pi.TypesInfo.ObjectOf(l).(*types.Var); ok {
+			return v.Origin()
+		}
+	case *ast.SelectorExpr:
+		if s, ok := pi.TypesInfo.Selections[l]; ok && s.Kind() == types.FieldVal {
+			return s.Obj().(*types.Var).Origin()
+		}
+	}
+
+	return nil
+}
+
+func buildAssignIndex(pi *packages.Package) *assignIndex {
+	idx := &assignIndex{values: make(map[*types.Var][]ast.Expr), params: make(map[*types.Var]bool)}
+
+	add := func(l ast.Expr, r ast.Expr) {
+		if v := exprVar(pi, l); v != nil {
+			idx.values[v] = append(idx.values[v], r)
+		}
+	}
+
+	for _, f := range pi.Syntax {
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch e := n.(type) {
+			case *ast.AssignStmt:
+				for i, l := range e.Lhs {
+					var r ast.Expr
+					if len(e.Rhs) == len(e.Lhs) && (e.Tok == token.ASSIGN || e.Tok == token.DEFINE) {
+						r = e.Rhs[i]
+					}
+
+					add(l, r)
+				}
+			case *ast.ValueSpec:
+				for i, l := range e.Names {
+					if len(e.Values) == len(e.Names) {
+						add(l, e.Values[i])
+					} else if len(e.Values) > 0 {
+						add(l, nil)
+					}
+				}
+			case *ast.RangeStmt:
+				if e.Key != nil {
+					add(e.Key, nil)
+				}
+
+				if e.Value != nil {
+					add(e.Value, nil)
+				}
+			case *ast.UnaryExpr:
+				if e.Op == token.AND {
+					add(e.X, nil) // address taken, can be modified indirectly
+				}
+			case *ast.CompositeLit:
+				indexCompositeLit(pi, e, idx)
+			case *ast.FuncType:
+				for _, l := range []*ast.FieldList{e.Params, e.Results} {
+					if l == nil {
+						continue
+					}
+
+					for _, p := range l.List {
+						for _, name := range p.Names {
+							if v, ok := pi.TypesInfo.Defs[name].(*types.Var); ok {
+								idx.params[v.Origin()] = true
+							}
+						}
+					}
+				}
+			case *ast.FuncDecl:
+				if e.Recv != nil {
+					for _, p := range e.Recv.List {
+						for _, name := range p.Names {
+							if v, ok := pi.TypesInfo.Defs[name].(*types.Var); ok {
+								idx.params[v.Origin()] = true
+							}
+						}
+					}
+				}
+			}
+
+			return true
+		})
+	}
+
+	return idx
+}
+
+// indexCompositeLit records struct fields initialized by composite literal.
+func indexCompositeLit(pi *packages.Package, e *ast.CompositeLit, idx *assignIndex) {
+	tp := pi.TypesInfo.TypeOf(e)
+	if tp == nil {
+		return
+	}
+
+	if p, ok := tp.Underlying().(*types.Pointer); ok {
+		tp = p.Elem()
+	}
+
+	st, ok := tp.Underlying().(*types.Struct)
+	if !ok {
+		return
+	}
+
+	for i, v := range e.Elts {
+		if kv, ok := v.(*ast.KeyValueExpr); ok {
+			if key, ok := kv.Key.(*ast.Ident); ok {
+				if fv, ok := pi.TypesInfo.Uses[key].(*types.Var); ok {
+					idx.values[fv.Origin()] = append(idx.values[fv.Origin()], kv.Value)
+				}
+			}
+
+			continue
+		}
+
+		if i < st.NumFields() {
+			fv := st.Field(i).Origin()
+			idx.values[fv] = append(idx.values[fv], v)
+		}
+	}
+}
+
+func unparen(e ast.Expr) ast.Expr {
+	for {
+		p, ok := e.(*ast.ParenExpr)
+		if !ok {
+			return e
+		}
+
+		e = p.X
+	}
+}
+
+// stripCallee removes parenthesis and instantiation from the function expression.
+func stripCallee(e ast.Expr) ast.Expr {
+	for {
+		switch f := e.(type) {
+		case *ast.ParenExpr:
+			e = f.X
+		case *ast.IndexExpr:
+			e = f.X
+		case *ast.IndexListExpr:
+			e = f.X
+		default:
+			return e
+		}
+	}
+}
+
+// resolveCallee returns the functions which can be called by the
+// call expression.
+// Non-empty reason is returned when the callee can't be determined statically.
+func (g *generator) resolveCallee(pi *packages.Package, fun ast.Expr, seen map[*types.Var]bool) ([]*types.Func, string) {
+	switch e := stripCallee(fun).(type) {
+	case *ast.Ident:
+		return g.resolveCalleeObject(pi, pi.TypesInfo.Uses[e], seen)
+	case *ast.SelectorExpr:
+		if s, ok := pi.TypesInfo.Selections[e]; ok {
+			return g.resolveCalleeObject(pi, s.Obj(), seen)
+		}
+
+		// qualified identifier
+		return g.resolveCalleeObject(pi, pi.TypesInfo.Uses[e.Sel], seen)
+	case *ast.FuncLit:
+		return nil, "function literal"
+	case *ast.CallExpr:
+		if isConversion(pi, e) {
+			return g.resolveCallee(pi, e.Args[0], seen)
+		}
+
+		return nil, "function returned by the call"
+	case *ast.ArrayType, *ast.MapType, *ast.ChanType, *ast.FuncType, *ast.InterfaceType, *ast.StructType,
+		*ast.StarExpr:
+		return nil, "" // conversion
+	}
+
+	return nil, fmt.Sprintf("unsupported callee expression %v", types.ExprString(fun))
+}
+
+func (g *generator) resolveCalleeObject(pi *packages.Package, obj types.Object, seen map[*types.Var]bool) (
+	[]*types.Func, string,
+) {
+	switch o := obj.(type) {
+	case *types.Func:
+		return []*types.Func{o.Origin()}, ""
+	case *types.Var:
+		vals, reason := g.varValues(pi, o.Origin(), seen)
+		if reason != "" {
+			return nil, reason
+		}
+
+		var res []*types.Func
+
+		for _, v := range vals {
+			fns, reason := g.resolveCallee(pi, v, seen)
+			if reason != "" {
+				return nil, reason
+			}
+
+			res = append(res, fns...)
+		}
+
+		return res, ""
+	case *types.Builtin, *types.TypeName, nil:
+		return nil, ""
+	}
+
+	return nil, fmt.Sprintf("unsupported callee object %v", obj)
+}
+
+// varValues returns non-nil values assigned to the variable or struct field
+// in the package.
+// All the assignments are returned regardless of their order and
+// reachability, see assignIndex, so the result is a superset of the
+// values the variable can hold at any particular use.
+// Non-empty reason is returned when the set of values can't be bounded.
+func (g *generator) varValues(pi *packages.Package, v *types.Var, seen map[*types.Var]bool) ([]ast.Expr, string) {
+	if seen[v] {
+		return nil, ""
+	}
+
+	seen[v] = true
+
+	idx := g.assignIndex(pi)
+
+	if idx.params[v] {
+		return nil, fmt.Sprintf("passed through function parameter '%v'", v.Name())
+	}
+
+	if v.Pkg() != nil && v.Pkg().Path() != pi.PkgPath {
+		return nil, fmt.Sprintf("variable '%v' of another package", v.Name())
+	}
+
+	// the assignments in the importing packages are not indexed
+	if pi.Name != "main" && v.Exported() {
+		if v.IsField() {
+			return nil, fmt.Sprintf("exported field '%v' can be assigned in other packages", v.Name())
+		}
+
+		if v.Pkg() != nil && v.Parent() == v.Pkg().Scope() {
+			return nil, fmt.Sprintf("exported variable '%v' can be assigned in other packages", v.Name())
+		}
+	}
+
+	vals := idx.values[v]
+	if len(vals) == 0 {
+		return nil, fmt.Sprintf("no assignments found for '%v'", v.Name())
+	}
+
+	var res []ast.Expr
+
+	for _, a := range vals {
+		if a == nil {
+			return nil, fmt.Sprintf("untracked assignment of '%v'", v.Name())
+		}
+
+		if !isNil(pi, a) {
+			res = append(res, a)
+		}
+	}
+
+	return res, ""
+}
+
+// funcValues returns the expressions the function argument can evaluate to,
+// following assignments of the variables and struct fields.
+func (g *generator) funcValues(pi *packages.Package, e ast.Expr, seen map[*types.Var]bool) []ast.Expr {
+	v := exprVar(pi, e)
+	if v == nil {
+		return []ast.Expr{unparen(e)}
+	}
+
+	vals, reason := g.varValues(pi, v, seen)
+	if reason != "" {
+		errorf(pi, e, "can't determine the function: %v", reason)
+	}
+
+	var res []ast.Expr
+
+	for _, a := range vals {
+		if ce, ok := unparen(a).(*ast.CallExpr); ok && !isConversion(pi, ce) {
+			errorf(pi, e, "can't determine the function: '%v' is assigned the result of the call %v",
+				v.Name(), types.ExprString(a))
+		}
+
+		res = append(res, g.funcValues(pi, a, seen)...)
+	}
+
+	return res
+}
+
+func isConversion(pi *packages.Package, ce *ast.CallExpr) bool {
+	tv, ok := pi.TypesInfo.Types[ce.Fun]
+
+	return ok && tv.IsType() && len(ce.Args) == 1
+}
+
+func isNil(pi *packages.Package, e ast.Expr) bool {
+	tv, ok := pi.TypesInfo.Types[e]
+
+	return ok && tv.IsNil()
+}
+
+// funcKey returns package path and the name of the function in the form
+// used by the API catalog: "Name" for functions and "Type.Method" for methods.
+func funcKey(fn *types.Func) (string, string) {
+	if fn.Pkg() == nil {
+		return "", fn.Name()
+	}
+
+	sig, _ := fn.Type().(*types.Signature)
+	if sig == nil || sig.Recv() == nil {
+		return fn.Pkg().Path(), fn.Name()
+	}
+
+	t := sig.Recv().Type()
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+
+	if n, ok := t.(*types.Named); ok {
+		return fn.Pkg().Path(), n.Obj().Name() + "." + fn.Name()
+	}
+
+	return fn.Pkg().Path(), fn.Name()
+}
@@ -0,0 +1,200 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"text/template"
+
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPICallForms(t *testing.T) {
+	g := newGenerator(context.Background(), &Config{
+		APIPackage: testAPIPackage,
+		APIs: []APIPackage{{
+			Path:      testAPIPackage,
+			Functions: []APIFunc{{Name: "NativeCollection.Match", Filters: []int{0}}},
+		}},
+		Log: &log.Logger,
+	})
+
+	pkgs, err := g.loadProgram([]string{"./testdata/callforms"})
+	require.NoError(t, err)
+	require.Len(t, pkgs, 1)
+
+	f, _, diags := g.findAndParse(pkgs[0])
+	require.Empty(t, diags)
+
+	res := make(map[string]string)
+	for _, v := range f {
+		res[v.Name] = v.Body
+	}
+
+	const prefix = "github.com/tigrisdata/tigrisgen/generate/testdata/callforms."
+
+	assert.Equal(t, map[string]string{
+		prefix + "FilterDot":         `{"field":{{toJSON .Arg.Value}}}`,
+		prefix + "FilterFuncValue":   `{"field":{"$gt":{{toJSON .Arg.Value}}}}`,
+		prefix + "FilterReassigned":  `{"field":{"$gte":{{toJSON .Arg.Value}}}}`,
+		prefix + "FilterMethodValue": `{"field":{"$lt":{{toJSON .Arg.Field}}}}`,
+	}, res)
+}
+
+func TestAPIWrappers(t *testing.T) {
+	g := newGenerator(context.Background(), &Config{APIPackage: testAPIPackage, Log: &log.Logger})
+
+	pkgs, err := g.loadProgram([]string{"./testdata/wrappers"})
+	require.NoError(t, err)
+	require.Len(t, pkgs, 1)
+
+	f, u, diags := g.findAndParse(pkgs[0])
+	require.Empty(t, diags)
+
+	res := make(map[string]string)
+	for _, v := range append(f, u...) {
+		res[v.Name] = v.Body
+	}
+
+	const prefix = "github.com/tigrisdata/tigrisgen/generate/testdata/wrappers."
+
+	assert.Equal(t, map[string]string{
+		prefix + "FilterFind":   `{"field":{{toJSON .Arg.Value}}}`,
+		prefix + "FilterModify": `{"field":{"$gt":{{toJSON .Arg.Value}}}}`,
+		prefix + "UpdateModify": `{"$set":{"field":{{toJSON .Arg}}}}`,
+		prefix + "FilterActive": `{"field":{"$ne":{{toJSON .Arg.Value}}}}`,
+		prefix + "FilterDelete": `{"field":{"$lt":{{toJSON .Arg.Value}}}}`,
+	}, res)
+
+	assert.Equal(t, &APIFunc{Name: "Repo.Find", Filters: []int{1}},
+		g.apis.lookup(prefix[:len(prefix)-1], "Repo.Find"))
+}
+
+func TestFuncValues(t *testing.T) {
+	g := newGenerator(context.Background(), &Config{APIPackage: testAPIPackage, Log: &log.Logger})
+
+	pkgs, err := g.loadProgram([]string{"./testdata/funcvalues"})
+	require.NoError(t, err)
+	require.Len(t, pkgs, 1)
+
+	f, u, diags := g.findAndParse(pkgs[0])
+
+	require.Len(t, diags, 4)
+	assert.Equal(t, "can't determine the function: 'p' is assigned the result of the call pick(admin)", diags[0].Msg)
+	assert.Equal(t, "p", diags[0].Expr)
+	assert.Equal(t, "can't determine the function: exported field 'Filter' can be assigned in other packages", diags[1].Msg)
+	assert.Equal(t, "e.Filter", diags[1].Expr)
+	assert.Equal(t, "can't determine the function: exported variable 'DefaultFilter' can be assigned in other packages",
+		diags[2].Msg)
+	assert.Equal(t, "DefaultFilter", diags[2].Expr)
+	assert.Equal(t, "can't determine the function: 'r' is assigned the result of the call pick(admin)", diags[3].Msg)
+	assert.Equal(t, "r", diags[3].Expr)
+
+	res := make(map[string]string)
+	for _, v := range append(f, u...) {
+		res[v.Name] = v.Body
+	}
+
+	const prefix = "github.com/tigrisdata/tigrisgen/generate/testdata/funcvalues."
+
+	assert.Equal(t, map[string]string{
+		prefix + "FilterActive": `{"field":{{toJSON .Arg.Value}}}`,
+		prefix + "FilterAll":    `{"field":{"$gte":{{toJSON .Arg.Value}}}}`,
+		prefix + "FilterField":  `{"field":{"$lt":{{toJSON .Arg.Value}}}}`,
+		prefix + "UpdateField":  `{"$set":{"field":{{toJSON .Arg}}}}`,
+	}, res)
+}
+
+func TestClosures(t *testing.T) {
+	g := newGenerator(context.Background(), &Config{APIPackage: testAPIPackage, Log: &log.Logger})
+
+	pkgs, err := g.loadProgram([]string{"./testdata/closures"})
+	require.NoError(t, err)
+	require.Len(t, pkgs, 1)
+
+	f, u, diags := g.findAndParse(pkgs[0])
+	require.Empty(t, diags)
+
+	res := make(map[string]string)
+	captured := make(map[string][]string)
+
+	for _, v := range append(f, u...) {
+		res[v.Name] = v.Body
+
+		if len(v.Captured) > 0 {
+			captured[v.Name] = v.Captured
+		}
+	}
+
+	const prefix = "github.com/tigrisdata/tigrisgen/generate/testdata/closures."
+
+	assert.Equal(t, map[string]string{
+		prefix + "Calls.func1":        `{"field":{{toJSON .Arg.Value}}}`,
+		prefix + "Calls.func2":        `{"field":{"$gt":{{toJSON (captured $ "min")}}}}`,
+		prefix + "Calls.func3":        `{"$and":[{"field":{"$lt":{{toJSON (captured $ "lim").Max}}}},{"field":{"$ne":{{toJSON .Arg.Value}}}}]}`,
+		prefix + "Calls.func4":        `{"$set":{"field":{{toJSON (captured $ "lim").Max}}}}`,
+		prefix + "Calls.func5.1":      `{"field":{"$gte":{{toJSON (captured $ "min")}}}}`,
+		prefix + "init.func1":         `{"field":{"$ne":{{toJSON .Arg.Value}}}}`,
+		prefix + "(*Repo).Find.func1": `{"field":{"$lte":{{toJSON .Arg.Value}}}}`,
+	}, res)
+
+	assert.Equal(t, map[string][]string{
+		prefix + "Calls.func2":   {"min"},
+		prefix + "Calls.func3":   {"lim"},
+		prefix + "Calls.func4":   {"lim"},
+		prefix + "Calls.func5.1": {"min"},
+	}, captured)
+
+	tmpl, err := template.New("closure").Funcs(tigrisFuncs).Parse(res[prefix+"Calls.func3"])
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	err = tmpl.Execute(&buf, map[string]any{
+		"Arg":      map[string]any{"Value": 1},
+		"Captured": map[string]any{"lim": struct{ Max int }{Max: 10}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `{"$and":[{"field":{"$lt":10}},{"field":{"$ne":1}}]}`, buf.String())
+
+	err = tmpl.Execute(&buf, map[string]any{"Arg": map[string]any{"Value": 1}})
+	assert.ErrorContains(t, err, "value of the captured variable 'lim' is not supplied")
+}
+
+func TestMainPackage(t *testing.T) {
+	g := newGenerator(context.Background(), &Config{APIPackage: testAPIPackage, Log: &log.Logger})
+
+	pkgs, err := g.loadProgram([]string{"./testdata/mainpkg"})
+	require.NoError(t, err)
+	require.Len(t, pkgs, 1)
+
+	f, _, diags := g.findAndParse(pkgs[0])
+	require.Empty(t, diags)
+
+	res := make(map[string]string)
+	for _, v := range f {
+		res[v.Name] = v.Body
+	}
+
+	assert.Equal(t, map[string]string{
+		"main.FilterMain":   `{"field":{{toJSON .Arg.Value}}}`,
+		"main.Item.Greater": `{"field":{"$gt":{{toJSON .Arg.Value}}}}`,
+		"main.main.func1":   `{"field":{"$lt":{{toJSON .Arg.Value}}}}`,
+	}, res)
+}
@@ -65,9 +65,13 @@ func parseTestClientEval_and_arg_first(d *Doc, args Args) bool {
 	return args.ArgInt != 10 && d.FieldFloat > 100 || d.FieldFloat == args.NestedArg.ArgFloat
 }
 
-// Error:
+// Filter:
 //
-//	Client side evaluated expressions are not allowed in the OR condition These are the expressions which doesn't include document fields
+//	{{ if ne .Arg.ArgInt 10 }}
+//		{}
+//	{{else}}
+//		{"field_float":{"$gt":100}}
+//	{{end}}
 func parseTestClientEval_or(d *Doc, args Args) bool {
 	return args.ArgInt != 10 || d.FieldFloat > 100
 }
@@ -87,14 +91,17 @@ func parseTestClientEval_if(d *Doc, args Args) bool {
 
 // Filter:
 //
+//	{{ if or ( and ( eq .Arg.ArgBool true ) ( ne .Arg.ArgInt 10 ) ) ( and ( or ( ne .Arg.ArgBool true ) ( eq .Arg.ArgInt 10 ) ) ( eq .Arg.ArgInt 110 ) ) }}
 //	{"$or":[
 //		{{ if and ( eq .Arg.ArgBool true ) ( ne .Arg.ArgInt 10 ) }}
-//			{"field_float":{{toJSON .Arg.ArgFloat}}},
+//			{"field_float":{{toJSON .Arg.ArgFloat}}}
 //		{{end}}
 //		{{ if and ( or ( ne .Arg.ArgBool true ) ( eq .Arg.ArgInt 10 ) ) ( eq .Arg.ArgInt 110 ) }}
+//			{{ if and ( eq .Arg.ArgBool true ) ( ne .Arg.ArgInt 10 ) }},{{end}}
 //			{"field_float":{"$ne":{{toJSON .Arg.ArgFloat}}}}
 //		{{end}}
 //	]}
+//	{{end}}
 func parseTestClientEval_if_nested(d *Doc, args Args) bool {
 	if args.ArgBool {
 		if args.ArgInt != 10 {
@@ -107,17 +114,21 @@ func parseTestClientEval_if_nested(d *Doc, args Args) bool {
 
 // Filter:
 //
+//	{{ if or ( eq .Arg.ArgBool true ) ( and ( ne .Arg.ArgBool true ) ( eq .Arg.ArgInt 18 ) ) ( and ( ne .Arg.ArgBool true ) ( ne .Arg.ArgInt 18 ) ( eq .Arg.ArgInt 110 ) ) }}
 //	{"$or":[
 //		{{ if eq .Arg.ArgBool true }}
-//			{"field_float":{{toJSON .Arg.ArgFloat}}},
+//			{"field_float":{{toJSON .Arg.ArgFloat}}}
 //		{{end}}
 //		{{ if and ( ne .Arg.ArgBool true ) ( eq .Arg.ArgInt 18 ) }}
-//			{"field_string":"val1"},
+//			{{ if eq .Arg.ArgBool true }},{{end}}
+//			{"field_string":"val1"}
 //		{{end}}
 //		{{ if and ( ne .Arg.ArgBool true ) ( ne .Arg.ArgInt 18 ) ( eq .Arg.ArgInt 110 ) }}
+//			{{ if or ( eq .Arg.ArgBool true ) ( and ( ne .Arg.ArgBool true ) ( eq .Arg.ArgInt 18 ) ) }},{{end}}
 //			{"field_float":{"$ne":{{toJSON .Arg.ArgFloat}}}}
 //		{{end}}
 //	]}
+//	{{end}}
 func parseTestClientEval_if_else(d *Doc, args Args) bool {
 	if args.ArgBool {
 		return d.FieldFloat == args.ArgFloat
@@ -128,14 +139,250 @@ func parseTestClientEval_if_else(d *Doc, args Args) bool {
 	return args.ArgInt == 110 && d.FieldFloat != args.ArgFloat
 }
 
+// Filter:
+//
+//	{{ if eq .Arg.ArgString "" }}
+//		{}
+//	{{else}}
+//		{"field_string":{{toJSON .Arg.ArgString}}}
+//	{{end}}
+func parseTestClientEval_or_optional(d *Doc, args Args) bool {
+	return args.ArgString == "" || d.FieldString == args.ArgString
+}
+
+// Filter:
+//
+//	{"$and":[
+//		{"field_int":{"$gt":0}},
+//		{{ if eq .Arg.ArgString "" }}
+//			{}
+//		{{else}}
+//			{"field_string":{{toJSON .Arg.ArgString}}}
+//		{{end}},
+//		{{ if eq .Arg.ArgInt 0 }}
+//			{}
+//		{{else}}
+//			{"field_int":{"$lt":{{toJSON .Arg.ArgInt}}}}
+//		{{end}}
+//	]}
+func parseTestClientEval_or_optional_and(d *Doc, args Args) bool {
+	return d.FieldInt > 0 && (args.ArgString == "" || d.FieldString == args.ArgString) &&
+		(args.ArgInt == 0 || d.FieldInt < args.ArgInt)
+}
+
+// Filter:
+//
+//	{{ if or ( eq .Arg.ArgBool true ) ( and ( eq .Arg.ArgInt 0 ) ( eq .Arg.ArgFloat 0.0 ) ) }}
+//		{}
+//	{{else}}
+//		{"$or":[{"field_int":{{toJSON .Arg.ArgInt}}},{"field_float":{{toJSON .Arg.ArgFloat}}}]}
+//	{{end}}
+func parseTestClientEval_or_multiple(d *Doc, args Args) bool {
+	return args.ArgBool || args.ArgInt == 0 && args.ArgFloat == 0 || d.FieldInt == args.ArgInt || d.FieldFloat == args.ArgFloat
+}
+
+// Filter:
+//
+//	{{ if eq .Arg.ArgString "" }}
+//		{}
+//	{{else}}
+//		{"$and":[
+//			{"field_string":{{toJSON .Arg.ArgString}}},
+//			{{ if eq .Arg.ArgInt 0 }}
+//				{}
+//			{{else}}
+//				{"field_int":{{toJSON .Arg.ArgInt}}}
+//			{{end}}
+//		]}
+//	{{end}}
+func parseTestClientEval_or_nested(d *Doc, args Args) bool {
+	return args.ArgString == "" || d.FieldString == args.ArgString && (args.ArgInt == 0 || d.FieldInt == args.ArgInt)
+}
+
+// Filter:
+//
+//	{{ if or ( gt .Arg.ArgInt 0 ) ( eq .Arg.ArgBool true ) }}
+//	{{ if eq .Arg.ArgBool true }}
+//		{}
+//	{{else}}
+//		{"$or":[
+//			{{ if gt .Arg.ArgInt 0 }}
+//				{"field_int":{{toJSON .Arg.ArgInt}}}
+//			{{end}}
+//		]}
+//	{{end}}
+//	{{end}}
+func parseTestClientEval_or_guarded(d *Doc, args Args) bool {
+	return args.ArgBool || args.ArgInt > 0 && d.FieldInt == args.ArgInt
+}
+
+// Filter:
+//
+//	{"$or":[
+//		{"field_int":0}
+//		{{ if or ( eq (isNil .Arg.ArgPtrStr) false ) ( eq (isNil .Arg.ArgPtrInt) false ) }},
+//			{"$and":[
+//				{"field_float":1},
+//				{"$or":[
+//					{{ if eq (isNil .Arg.ArgPtrStr) false }}
+//						{"field_string":{{toJSON (deref .Arg.ArgPtrStr)}}}
+//					{{end}}
+//					{{ if eq (isNil .Arg.ArgPtrInt) false }}
+//						{{ if eq (isNil .Arg.ArgPtrStr) false }},{{end}}
+//						{"field_int":{{toJSON (deref .Arg.ArgPtrInt)}}}
+//					{{end}}
+//				]}
+//			]}
+//		{{end}}
+//	]}
+func parseTestClientEval_or_guarded_nested(d *Doc, args Args) bool {
+	return d.FieldInt == 0 || d.FieldFloat == 1 &&
+		(args.ArgPtrStr != nil && d.FieldString == *args.ArgPtrStr || args.ArgPtrInt != nil && d.FieldInt == *args.ArgPtrInt)
+}
+
+// Filter:
+//
+//	{{ if eq (isNil .Arg.ArgPtrStr) true }}
+//		{}
+//	{{else}}
+//		{"field_string":{{toJSON (deref .Arg.ArgPtrStr)}}}
+//	{{end}}
+func parseTestClientEval_optional_arg(d *Doc, args Args) bool {
+	return args.ArgPtrStr == nil || d.FieldString == *args.ArgPtrStr
+}
+
+// Filter:
+//
+//	{"$and":[
+//		{{ if ne (isNil .Arg.ArgPtrStr) false }}
+//			{}
+//		{{else}}
+//			{"field_string":{{toJSON (deref .Arg.ArgPtrStr)}}}
+//		{{end}},
+//		{{ if ne (isNil .Arg.ArgPtrInt) false }}
+//			{}
+//		{{else}}
+//			{"field_int":{"$gte":{{toJSON (deref .Arg.ArgPtrInt)}}}}
+//		{{end}}
+//	]}
+func parseTestClientEval_optional_guard(d *Doc, args Args) bool {
+	if args.ArgPtrStr != nil && d.FieldString != *args.ArgPtrStr {
+		return false
+	}
+
+	if args.ArgPtrInt != nil && d.FieldInt < *args.ArgPtrInt {
+		return false
+	}
+
+	return true
+}
+
+// Filter:
+//
+//	{{ if or ( eq (isNil .Arg.ArgPtrInt) false ) ( ne (isNil .Arg.ArgPtrInt) false ) }}
+//	{"$or":[
+//		{{ if eq (isNil .Arg.ArgPtrInt) false }}
+//			{"field_int":{"$gte":{{toJSON (deref .Arg.ArgPtrInt)}}}}
+//		{{end}}
+//		{{ if ne (isNil .Arg.ArgPtrInt) false }}
+//			{{ if eq (isNil .Arg.ArgPtrInt) false }},{{end}}
+//			{"field_bool":true}
+//		{{end}}
+//	]}
+//	{{end}}
+func parseTestClientEval_optional_if(d *Doc, args Args) bool {
+	if args.ArgPtrInt != nil {
+		return d.FieldInt >= *args.ArgPtrInt
+	}
+
+	return d.FieldBool
+}
+
+// Filter:
+//
+//	{{ if le .Arg.ArgInt .Arg.NestedArg.ArgInt }}
+//		{"$and":[{"field_int":{"$gte":{{toJSON .Arg.ArgInt}}}},{"field_int":{"$lte":{{toJSON .Arg.NestedArg.ArgInt}}}}]}
+//	{{end}}
+func parseTestClientEval_arg_cmp(d *Doc, args Args) bool {
+	if args.ArgInt > args.NestedArg.ArgInt {
+		return false
+	}
+
+	return d.FieldInt >= args.ArgInt && d.FieldInt <= args.NestedArg.ArgInt
+}
+
+// Filter:
+//
+//	{{ if and ( le (timeCmp .Arg.ArgTime .Arg.NestedArg.ArgTime) 0 ) ( eq (timeCmp .Arg.ArgTime .Arg.NestedArg.ArgTime) 0 ) ( le (timeCmp .Arg.ArgTime .Arg.NestedArg.ArgTime) 0 ) ( ge (timeCmp .Arg.NestedArg.ArgTime .Arg.ArgTime) 0 ) }}
+//		{"field_time":{"$gt":{{toJSON .Arg.ArgTime}}}}
+//	{{end}}
+func parseTestClientEval_arg_cmp_time(d *Doc, args Args) bool {
+	if args.ArgTime.After(args.NestedArg.ArgTime) || !args.ArgTime.Equal(args.NestedArg.ArgTime) {
+		return false
+	}
+
+	return args.ArgTime.Compare(args.NestedArg.ArgTime) <= 0 && !args.NestedArg.ArgTime.Before(args.ArgTime) &&
+		d.FieldTime.After(args.ArgTime)
+}
+
+// Filter:
+//
+//	{{ if or ( eq .Arg.ArgString .Arg.NestedArg.ArgString ) ( lt 10 (mul .Arg.ArgInt 2) ) }}
+//		{}
+//	{{else}}
+//		{"field_string":{{toJSON .Arg.ArgString}}}
+//	{{end}}
+func parseTestClientEval_arg_cmp_or(d *Doc, args Args) bool {
+	return args.ArgString == args.NestedArg.ArgString || 10 < args.ArgInt*2 || d.FieldString == args.ArgString
+}
+
+// Filter:
+//
+//	{{ if or ( lt (float .Arg.ArgInt32) .Arg.ArgFloat ) ( eq .Arg.ArgFloat 2.0 ) }}
+//		{}
+//	{{else}}
+//		{"field_float":{"$gt":{{toJSON (float .Arg.ArgInt32)}}}}
+//	{{end}}
+func parseTestClientEval_arg_cmp_float(d *Doc, args Args) bool {
+	return float64(args.ArgInt32) < args.ArgFloat || args.ArgFloat == 2 || d.FieldFloat > float64(args.ArgInt32)
+}
+
+// Filter:
+//
+//	{{ if or ( ne .Arg.ArgFloat 2.0 ) ( gt .Arg.ArgFloat 1e+21 ) }}
+//	{"$or":[
+//		{{ if ne .Arg.ArgFloat 2.0 }}
+//			{"field_float":{"$gt":{{toJSON .Arg.ArgFloat}}}}
+//		{{end}}
+//		{{ if gt .Arg.ArgFloat 1e+21 }}
+//			{{ if ne .Arg.ArgFloat 2.0 }},{{end}}
+//			{"$and":[
+//				{{ if eq .Arg.ArgFloat 2.0 }}
+//					{}
+//				{{else}}
+//					{"field_float":{"$lte":{{toJSON .Arg.ArgFloat}}}}
+//				{{end}},
+//				{"field_int":0}
+//			]}
+//		{{end}}
+//	]}
+//	{{end}}
+func parseTestClientEval_optional_float(d *Doc, args Args) bool {
+	if args.ArgFloat != 2 && d.FieldFloat > args.ArgFloat {
+		return true
+	}
+
+	return args.ArgFloat > 1e21 && d.FieldInt == 0
+}
+
 func TestFiltersClientEval(t *testing.T) {
 	execTests(t, "parseTestClientEval_", false)
 }
 
 // Update:
 //
-//	{"$set":{
-//		{{ if eq .Arg.ArgInt 10 }}
+//	{"$set":
+//		{ {{ if eq .Arg.ArgInt 10 }}
 //			"field_int":10
 //		{{end}}
 //		{{ if eq .Arg.ArgInt 10 }},{{end}}
@@ -207,8 +454,8 @@ func parseTestUpdateClientEval_string(d *Doc, args Args) {
 
 // Update:
 //
-//	{"$set":{
-//		{{ if eq .Arg.ArgString "qwerty" }}"field_string":"abc"{{end}}
+//	{"$set":
+//		{ {{ if eq .Arg.ArgString "qwerty" }}"field_string":"abc"{{end}}
 //		{{ if eq .Arg.ArgInt 10 }},"field_int":22{{end}}
 //		{{ if eq .Arg.ArgFloat 3.3 }},"field_float":5.5{{end}}
 //		{{ if or ( eq .Arg.ArgString "qwerty" ) ( eq .Arg.ArgInt 10 ) ( eq .Arg.ArgFloat 3.3 ) }},{{end}}
@@ -232,8 +479,8 @@ func parseTestUpdateClientEval_multiple_optional(d *Doc, args Args) {
 
 // Update:
 //
-//	{"$set":{
-//		{{ if eq .Arg.ArgInt 10 }}
+//	{"$set":
+//		{ {{ if eq .Arg.ArgInt 10 }}
 //			"field_int":22
 //			{{ if eq .Arg.ArgString "qwerty" }},
 //				{{ if eq .Arg.ArgFloat 3.3 }}
@@ -263,8 +510,8 @@ func parseTestUpdateClientEval_nested(d *Doc, args Args) {
 
 // Update:
 //
-//	{"$set":{
-//		{{ if eq .Arg.ArgInt 10 }}
+//	{"$set":
+//		{ {{ if eq .Arg.ArgInt 10 }}
 //			"field_int":22
 //			{{ if eq .Arg.ArgString "qwerty" }},
 //				{{ if eq .Arg.ArgFloat 3.3 }}
@@ -276,25 +523,25 @@ func parseTestUpdateClientEval_nested(d *Doc, args Args) {
 //			"field_string":"uuu"
 //		{{end}}
 //	},
-//	"$increment":{
-//		{{ if eq .Arg.ArgInt 10 }}
+//	"$increment":
+//		{ {{ if eq .Arg.ArgInt 10 }}
 //			"field_int":22
 //		{{end}}
 //	},
-//	"$divide":{
-//		{{ if eq .Arg.ArgInt 10 }}
+//	"$divide":
+//		{ {{ if eq .Arg.ArgInt 10 }}
 //			{{ if eq .Arg.ArgString "qwerty" }}
 //				"nested.field_int":888
 //			{{end}}
 //		{{end}}
 //	},
-//	"$multiply":{
-//		{{ if eq .Arg.ArgInt 10 }}
+//	"$multiply":
+//		{ {{ if eq .Arg.ArgInt 10 }}
 //			"nested.field_float":777
 //		{{end}}
 //	},
-//	"$push":{
-//		{{ if eq .Arg.ArgInt 10 }}
+//	"$push":
+//		{ {{ if eq .Arg.ArgInt 10 }}
 //			{{ if eq .Arg.ArgString "qwerty" }}
 //				{{ if eq .Arg.ArgFloat 3.3 }}
 //					"field_arr_float":5.5
@@ -322,6 +569,40 @@ func parseTestUpdateClientEval_multiop(d *Doc, args Args) {
 	}
 }
 
+// Filter:
+//
+//	{{ if or ( eq .Arg.ArgInt 1 ) ( and ( ne .Arg.ArgInt 1 ) ( or ( or ( eq .Arg.ArgInt 2 ) ( eq .Arg.ArgInt 3 ) ) ( and ( ne .Arg.ArgInt 2 ) ( ne .Arg.ArgInt 3 ) ) ) ) }}
+//	{"$or":[
+//		{{ if eq .Arg.ArgInt 1 }}
+//			{"field_int":{"$gt":1}}
+//		{{end}}
+//		{{ if and ( ne .Arg.ArgInt 1 ) ( or ( or ( eq .Arg.ArgInt 2 ) ( eq .Arg.ArgInt 3 ) ) ( and ( ne .Arg.ArgInt 2 ) ( ne .Arg.ArgInt 3 ) ) ) }}
+//			{{ if ne .Arg.ArgInt 1 }}
+//				{{ if eq .Arg.ArgInt 1 }},{{end}}
+//				{"$or":[
+//					{{ if or ( eq .Arg.ArgInt 2 ) ( eq .Arg.ArgInt 3 ) }}
+//						{"field_float":{{toJSON .Arg.ArgFloat}}}
+//					{{end}}
+//					{{ if and ( ne .Arg.ArgInt 2 ) ( ne .Arg.ArgInt 3 ) }}
+//						{{ if or ( eq .Arg.ArgInt 2 ) ( eq .Arg.ArgInt 3 ) }},{{end}}
+//						{"field_bool":true}
+//					{{end}}
+//				]}
+//			{{end}}
+//		{{end}}
+//	]}
+//	{{end}}
+func parseTestClientEval_switch(d *Doc, args Args) bool {
+	switch args.ArgInt {
+	case 1:
+		return d.FieldInt > 1
+	case 2, 3:
+		return d.FieldFloat == args.ArgFloat
+	default:
+		return d.FieldBool
+	}
+}
+
 // this is to fix the unused linter.
 var (
 	_ = parseTestClientEval_and
@@ -332,6 +613,21 @@ var (
 	_ = parseTestClientEval_if
 	_ = parseTestClientEval_if_nested
 	_ = parseTestClientEval_if_else
+	_ = parseTestClientEval_switch
+	_ = parseTestClientEval_or_optional
+	_ = parseTestClientEval_or_optional_and
+	_ = parseTestClientEval_or_multiple
+	_ = parseTestClientEval_or_nested
+	_ = parseTestClientEval_or_guarded
+	_ = parseTestClientEval_or_guarded_nested
+	_ = parseTestClientEval_arg_cmp_float
+	_ = parseTestClientEval_optional_float
+	_ = parseTestClientEval_optional_arg
+	_ = parseTestClientEval_optional_guard
+	_ = parseTestClientEval_optional_if
+	_ = parseTestClientEval_arg_cmp
+	_ = parseTestClientEval_arg_cmp_time
+	_ = parseTestClientEval_arg_cmp_or
 	_ = parseTestUpdateClientEval_first
 	_ = parseTestUpdateClientEval_last
 	_ = parseTestUpdateClientEval_middle
@@ -0,0 +1,206 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// enclosingFuncName returns the name of the declared function, as
+// reported by the runtime: "pkg.Func", "pkg.Type.Method", "pkg.(*Type).Method".
+func enclosingFuncName(pi *packages.Package, fd *ast.FuncDecl) string {
+	name := fd.Name.Name
+
+	if fd.Type.TypeParams != nil {
+		name += "[...]"
+	}
+
+	if fd.Recv != nil && len(fd.Recv.List) == 1 {
+		t := fd.Recv.List[0].Type
+
+		ptr := false
+		if s, ok := t.(*ast.StarExpr); ok {
+			ptr = true
+			t = s.X
+		}
+
+		generic := false
+
+		switch e := t.(type) {
+		case *ast.IndexExpr:
+			t, generic = e.X, true
+		case *ast.IndexListExpr:
+			t, generic = e.X, true
+		}
+
+		tn := types.ExprString(t)
+		if generic {
+			tn += "[...]"
+		}
+
+		if ptr {
+			tn = "(*" + tn + ")"
+		}
+
+		name = tn + "." + name
+	}
+
+	return pkgFuncName(pi, name)
+}
+
+// closureIndex looks up the function literal in the node, numbering
+// function literals in the order of appearance, the way compiler does.
+// Literals directly in the function are named prefix.funcN,
+// nested literals are named prefix.funcN.M, as the runtime reports
+// them when the enclosing literal is not inlined.
+func closureIndex(n ast.Node, lit *ast.FuncLit, prefix string, cnt *int) (string, bool) {
+	return closureIndexLow(n, lit, prefix+".func", cnt)
+}
+
+func closureIndexLow(n ast.Node, lit *ast.FuncLit, prefix string, cnt *int) (string, bool) {
+	var (
+		res   string
+		found bool
+	)
+
+	ast.Inspect(n, func(n ast.Node) bool {
+		fl, ok := n.(*ast.FuncLit)
+		if found || !ok {
+			return !found
+		}
+
+		*cnt++
+
+		name := fmt.Sprintf("%v%d", prefix, *cnt)
+
+		if fl == lit {
+			res, found = name, true
+			return false
+		}
+
+		if lit.Pos() >= fl.Pos() && lit.End() <= fl.End() {
+			var nested int
+
+			res, found = closureIndexLow(fl.Body, lit, name+".", &nested)
+		}
+
+		return false
+	})
+
+	return res, found
+}
+
+// closureName returns the stable unique name of the function literal.
+// The name matches the name of the closure reported by the runtime,
+// so as the client can look up the filter by the function value.
+func closureName(pi *packages.Package, lit *ast.FuncLit) string {
+	var glob, inits int
+
+	for _, f := range pi.Syntax {
+		for _, d := range f.Decls {
+			switch d := d.(type) {
+			case *ast.FuncDecl:
+				prefix := enclosingFuncName(pi, d)
+
+				if d.Recv == nil && d.Name.Name == "init" {
+					prefix = pkgFuncName(pi, fmt.Sprintf("init.%d", inits))
+					inits++
+				}
+
+				if d.Body == nil || lit.Pos() < d.Pos() || lit.End() > d.End() {
+					continue
+				}
+
+				var cnt int
+
+				if name, ok := closureIndex(d.Body, lit, prefix, &cnt); ok {
+					return name
+				}
+			case *ast.GenDecl:
+				// closures in package level variables initialization
+				if name, ok := closureIndex(d, lit, pkgFuncName(pi, "init"), &glob); ok {
+					return name
+				}
+			}
+		}
+	}
+
+	errorf(pi, lit, "enclosing function of the closure not found")
+
+	return ""
+}
+
+// captures holds the variables captured by the closure and the names
+// of the template arguments they are passed in.
+type captures struct {
+	vars map[*types.Var]string
+	used map[string]bool
+	// names in the order of the first use
+	names []string
+}
+
+func newCaptures() *captures {
+	return &captures{vars: make(map[*types.Var]string), used: make(map[string]bool)}
+}
+
+// capturedName returns the name of the template argument for the
+// variable captured by the closure.
+// Returns false if the identifier is not a captured variable.
+func (f *funcParser) capturedName(id *ast.Ident) (string, bool) {
+	v, ok := f.pi.TypesInfo.Uses[id].(*types.Var)
+	if !ok || v.IsField() || v.Parent() == nil || v.Pkg() == nil || v.Parent() == v.Pkg().Scope() {
+		return "", false
+	}
+
+	if v.Pos() >= f.fn.Pos() && v.Pos() < f.fn.End() {
+		return "", false // declared in the function
+	}
+
+	if name, ok := f.captured.vars[v]; ok {
+		return name, true
+	}
+
+	name := v.Name()
+
+	for i := 1; f.captured.used[name]; i++ {
+		name = fmt.Sprintf("%v_%d", v.Name(), i)
+	}
+
+	f.captured.vars[v] = name
+	f.captured.used[name] = true
+	f.captured.names = append(f.captured.names, name)
+
+	return name, true
+}
+
+// rootIdent returns the identifier the selector starts with.
+func rootIdent(e ast.Expr) *ast.Ident {
+	for {
+		switch x := e.(type) {
+		case *ast.SelectorExpr:
+			e = x.X
+		case *ast.IndexExpr:
+			e = x.X
+		case *ast.Ident:
+			return x
+		default:
+			return nil
+		}
+	}
+}
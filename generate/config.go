@@ -0,0 +1,200 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const ConfigFileName = "tigrisgen.yaml"
+
+// APIFunc describes the function which takes filter and
+// update functions as parameters.
+type APIFunc struct {
+	Name string `yaml:"name"`
+	// Positions of the filter function arguments.
+	Filters []int `yaml:"filters"`
+	// Positions of the update function arguments.
+	Updates []int `yaml:"updates"`
+}
+
+// APIPackage is the package containing API functions.
+type APIPackage struct {
+	Path      string    `yaml:"package"`
+	Functions []APIFunc `yaml:"functions"`
+}
+
+// FileConfig is the content of the tigrisgen.yaml.
+type FileConfig struct {
+	APIs []APIPackage `yaml:"apis"`
+}
+
+// defaultAPIFunctions are the API functions of the Tigris client package.
+var defaultAPIFunctions = []APIFunc{
+	{Name: "Update", Filters: []int{2}, Updates: []int{3}},
+	{Name: "UpdateOne", Filters: []int{2}, Updates: []int{3}},
+	{Name: "UpdateAll", Updates: []int{2}},
+	{Name: "Read", Filters: []int{2}},
+	{Name: "ReadOne", Filters: []int{2}},
+	{Name: "ReadWithOptions", Filters: []int{2}},
+	{Name: "Delete", Filters: []int{2}},
+	{Name: "DeleteOne", Filters: []int{2}},
+}
+
+// ReadConfigFile reads and validates tigrisgen.yaml.
+func ReadConfigFile(name string) (*FileConfig, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg FileConfig
+
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+
+	if err = dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("%v: %w", name, err)
+	}
+
+	if err = validateAPIs(cfg.APIs); err != nil {
+		return nil, fmt.Errorf("%v: %w", name, err)
+	}
+
+	return &cfg, nil
+}
+
+// FindConfigFile looks up tigrisgen.yaml in the directory and its parents.
+// Returns empty string if not found.
+func FindConfigFile(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		name := filepath.Join(dir, ConfigFileName)
+
+		_, err = os.Stat(name)
+		if err == nil {
+			return name, nil
+		}
+
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+
+		dir = parent
+	}
+}
+
+func validateAPIs(apis []APIPackage) error {
+	for _, p := range apis {
+		if p.Path == "" {
+			return fmt.Errorf("package path is required")
+		}
+
+		for _, f := range p.Functions {
+			if f.Name == "" {
+				return fmt.Errorf("function name is required in package '%v'", p.Path)
+			}
+
+			if len(f.Filters) == 0 && len(f.Updates) == 0 {
+				return fmt.Errorf("function '%v.%v' should have filter or update arguments", p.Path, f.Name)
+			}
+
+			for _, l := range [][]int{f.Filters, f.Updates} {
+				for _, v := range l {
+					if v < 0 {
+						return fmt.Errorf("function '%v.%v' has negative argument position: %v", p.Path, f.Name, v)
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// apiCatalog maps package path and function name to the API function description.
+type apiCatalog map[string]map[string]*APIFunc
+
+func newAPICatalog(apiPkg string, apis []APIPackage) apiCatalog {
+	c := apiCatalog{}
+
+	c.add(APIPackage{Path: apiPkg, Functions: defaultAPIFunctions})
+
+	for _, v := range apis {
+		c.add(v)
+	}
+
+	return c
+}
+
+func (c apiCatalog) add(p APIPackage) {
+	if c[p.Path] == nil {
+		c[p.Path] = make(map[string]*APIFunc)
+	}
+
+	for _, v := range p.Functions {
+		fn := APIFunc{Name: v.Name, Filters: append([]int(nil), v.Filters...), Updates: append([]int(nil), v.Updates...)}
+		c[p.Path][v.Name] = &fn
+	}
+}
+
+func (c apiCatalog) lookup(pkg string, name string) *APIFunc {
+	return c[pkg][name]
+}
+
+// addPosition adds filter or update argument position to the function,
+// adding the function to the catalog if necessary.
+// Returns false if the position is already in the catalog.
+func (c apiCatalog) addPosition(pkg string, name string, pos int, update bool) bool {
+	fn := c.lookup(pkg, name)
+	if fn == nil {
+		if c[pkg] == nil {
+			c[pkg] = make(map[string]*APIFunc)
+		}
+
+		fn = &APIFunc{Name: name}
+		c[pkg][name] = fn
+	}
+
+	l := &fn.Filters
+	if update {
+		l = &fn.Updates
+	}
+
+	for _, v := range *l {
+		if v == pos {
+			return false
+		}
+	}
+
+	*l = append(*l, pos)
+
+	return true
+}
@@ -0,0 +1,152 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadConfigFile(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		exp  *FileConfig
+		err  string
+	}{
+		{
+			name: "valid", body: `
+apis:
+  - package: github.com/acme/store
+    functions:
+      - name: Find
+        filters: [1]
+      - name: Modify
+        filters: [1]
+        updates: [2]
+`,
+			exp: &FileConfig{APIs: []APIPackage{{
+				Path: "github.com/acme/store",
+				Functions: []APIFunc{
+					{Name: "Find", Filters: []int{1}},
+					{Name: "Modify", Filters: []int{1}, Updates: []int{2}},
+				},
+			}}},
+		},
+		{
+			name: "unknown_field", body: `
+apis:
+  - package: github.com/acme/store
+    function: Find
+`,
+			err: "field function not found",
+		},
+		{
+			name: "no_positions", body: `
+apis:
+  - package: github.com/acme/store
+    functions:
+      - name: Find
+`,
+			err: "function 'github.com/acme/store.Find' should have filter or update arguments",
+		},
+		{
+			name: "negative_position", body: `
+apis:
+  - package: github.com/acme/store
+    functions:
+      - name: Find
+        filters: [-1]
+`,
+			err: "function 'github.com/acme/store.Find' has negative argument position: -1",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), ConfigFileName)
+			require.NoError(t, os.WriteFile(name, []byte(c.body), 0o600))
+
+			cfg, err := ReadConfigFile(name)
+			if c.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), c.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.exp, cfg)
+		})
+	}
+}
+
+func TestFindConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "a", "b")
+
+	require.NoError(t, os.MkdirAll(sub, 0o700))
+
+	name, err := FindConfigFile(sub)
+	require.NoError(t, err)
+
+	if name != "" {
+		// tigrisgen.yaml exists somewhere above the temp dir
+		assert.NotContains(t, name, dir)
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ConfigFileName), []byte("apis:\n"), 0o600))
+
+	name, err = FindConfigFile(sub)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, ConfigFileName), name)
+}
+
+func TestAPILookupConfigured(t *testing.T) {
+	g := newGenerator(context.Background(), &Config{
+		APIPackage: testAPIPackage,
+		APIs: []APIPackage{{
+			Path:      testAPIPackage,
+			Functions: []APIFunc{{Name: "Find", Filters: []int{0}}},
+		}},
+		Log: &log.Logger,
+	})
+
+	pkgs, err := g.loadProgram([]string{"."})
+	require.NoError(t, err)
+
+	var found bool
+
+	for _, pi := range pkgs {
+		f, _, diags := g.findAndParse(pi)
+		require.Empty(t, diags)
+
+		for _, v := range f {
+			if v.Name == "github.com/tigrisdata/tigrisgen/generate.FilterConfigured" {
+				assert.Equal(t, `{"field_string":{{toJSON .Arg.ArgString}}}`, v.Body)
+
+				found = true
+			}
+		}
+	}
+
+	assert.True(t, found)
+}
@@ -0,0 +1,178 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"go/ast"
+	"go/types"
+
+	"github.com/tigrisdata/tigrisgen/expr"
+	"golang.org/x/tools/go/packages"
+)
+
+// checkConversion reports conversions which can change the value of
+// the operand x, other conversions are transparent for the operands.
+// Integer argument converted to float64 is converted by the template
+// helper the same way as Go does, so it's not reported.
+func (f *funcParser) checkConversion(e *ast.CallExpr, x expr.Operand) {
+	from := f.pi.TypesInfo.TypeOf(e.Args[0])
+	to := f.pi.TypesInfo.TypeOf(e)
+
+	// the operand is marshaled by the type it has before the conversion
+	if !types.Identical(from, to) {
+		for _, tp := range []types.Type{to, from} {
+			if name := customMarshaler(tp); name != "" {
+				errorf(f.pi, e, "conversion from %v to %v with custom %v is not supported", from, to, name)
+			}
+		}
+	}
+
+	if types.Identical(from.Underlying(), to.Underlying()) {
+		return
+	}
+
+	if x.Type == expr.Arg && isFloatConversion(f.pi, e) && isFloat64(to) {
+		return
+	}
+
+	fb, ok := from.Underlying().(*types.Basic)
+	tb, ok1 := to.Underlying().(*types.Basic)
+
+	if !ok || !ok1 || !f.preservesValue(fb, tb) {
+		errorf(f.pi, e, "conversion from %v to %v can change the value", from, to)
+	}
+}
+
+// parseConversion returns the operand of the conversion. Conversions
+// are transparent for the operands, except the integer argument converted
+// to float, which is converted by the template, because the template
+// doesn't mix integers and floats in comparisons and arithmetic.
+func (f *funcParser) parseConversion(e *ast.CallExpr) expr.Operand {
+	x := f.parseOperand(e.Args[0])
+
+	f.checkConversion(e, x)
+
+	if x.Type == expr.Arg && isFloatConversion(f.pi, e) {
+		return expr.NewPipeline("float " + x.ArgPath())
+	}
+
+	return x
+}
+
+func isFloatConversion(pi *packages.Package, e *ast.CallExpr) bool {
+	return isFloat(pi.TypesInfo.TypeOf(e)) && !isFloat(pi.TypesInfo.TypeOf(e.Args[0]))
+}
+
+// valueType returns the type of the value of the expression passed
+// to the template, it's the type of the expression before the conversions,
+// which are transparent for the operands.
+func (f *funcParser) valueType(e ast.Expr) types.Type {
+	return f.pi.TypesInfo.TypeOf(stripConversions(f.pi, e))
+}
+
+// stripConversions returns the operand of the transparent conversions.
+func stripConversions(pi *packages.Package, e ast.Expr) ast.Expr {
+	for {
+		ce, ok := unparen(e).(*ast.CallExpr)
+		if !ok || !isConversion(pi, ce) || isFloatConversion(pi, ce) {
+			return e
+		}
+
+		e = ce.Args[0]
+	}
+}
+
+func isFloat(tp types.Type) bool {
+	b, ok := tp.Underlying().(*types.Basic)
+
+	return ok && b.Info()&types.IsFloat != 0
+}
+
+func isFloat64(tp types.Type) bool {
+	b, ok := tp.Underlying().(*types.Basic)
+
+	return ok && b.Kind() == types.Float64
+}
+
+// preservesValue returns true if every value of the basic type "from"
+// is represented exactly by the type "to".
+func (f *funcParser) preservesValue(from *types.Basic, to *types.Basic) bool {
+	sizes := f.pi.TypesSizes
+	if sizes == nil {
+		sizes = types.SizesFor("gc", "amd64")
+	}
+
+	fi, ti := from.Info(), to.Info()
+
+	switch {
+	case fi&types.IsInteger != 0 && ti&types.IsInteger != 0:
+		fs, ts := sizes.Sizeof(from), sizes.Sizeof(to)
+
+		switch {
+		case fi&types.IsUnsigned == ti&types.IsUnsigned:
+			return ts >= fs
+		case fi&types.IsUnsigned != 0:
+			return ts > fs
+		}
+
+		return false // signed to unsigned
+	case fi&types.IsInteger != 0 && ti&types.IsFloat != 0:
+		// the integer has to fit into the mantissa of the float
+		bits := 8 * sizes.Sizeof(from)
+		if fi&types.IsUnsigned == 0 {
+			bits--
+		}
+
+		mantissa := int64(53)
+		if sizes.Sizeof(to) == 4 {
+			mantissa = 24
+		}
+
+		return bits <= mantissa
+	case fi&types.IsFloat != 0 && ti&types.IsFloat != 0:
+		return sizes.Sizeof(to) >= sizes.Sizeof(from)
+	case fi&types.IsString != 0 && ti&types.IsString != 0:
+		return true
+	}
+
+	return false
+}
+
+// checkConstMarshaler reports the typed constants, which JSON value
+// differs from the underlying value because of custom marshaling.
+func (f *funcParser) checkConstMarshaler(e ast.Expr) {
+	tp := f.pi.TypesInfo.TypeOf(e)
+	if _, ok := tp.(*types.Named); !ok {
+		return
+	}
+
+	if name := customMarshaler(tp); name != "" {
+		errorf(f.pi, e, "constant of type %v with custom %v is not supported", tp, name)
+	}
+}
+
+// customMarshaler returns the name of the custom JSON marshaling method
+// of the type or empty string, if the type is marshaled by default.
+func customMarshaler(tp types.Type) string {
+	ms := types.NewMethodSet(types.NewPointer(tp))
+
+	for _, name := range []string{"MarshalJSON", "MarshalText"} {
+		if ms.Lookup(nil, name) != nil {
+			return name
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,220 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/tigrisdata/tigrisgen/util"
+	"golang.org/x/tools/go/packages"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+
+	return "error"
+}
+
+// Diagnostic describes a problem found while translating
+// filter or update function.
+type Diagnostic struct {
+	Severity Severity
+	Pos      token.Position
+	Func     string // name of the function being translated
+	Expr     string // source of the failing expression
+	Msg      string
+}
+
+func (d *Diagnostic) Error() string {
+	var sb strings.Builder
+
+	if d.Pos.IsValid() {
+		sb.WriteString(d.Pos.String())
+		sb.WriteString(": ")
+	}
+
+	sb.WriteString(d.Severity.String())
+	sb.WriteString(": ")
+	sb.WriteString(d.Msg)
+
+	if d.Expr != "" {
+		sb.WriteString(": ")
+		sb.WriteString(d.Expr)
+	}
+
+	if d.Func != "" {
+		sb.WriteString(" (in ")
+		sb.WriteString(d.Func)
+		sb.WriteString(")")
+	}
+
+	return sb.String()
+}
+
+// Diagnostics is the list of errors and warnings collected during the run.
+type Diagnostics []*Diagnostic
+
+func (l Diagnostics) Error() string {
+	s := make([]string, 0, len(l))
+
+	for _, v := range l {
+		s = append(s, v.Error())
+	}
+
+	return strings.Join(s, "\n")
+}
+
+func (l Diagnostics) HasErrors() bool {
+	for _, v := range l {
+		if v.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Err returns the list as an error if it contains at least one error.
+func (l Diagnostics) Err() error {
+	if !l.HasErrors() {
+		return nil
+	}
+
+	return l
+}
+
+func (l *Diagnostics) add(err error) {
+	var d *Diagnostic
+	if !errors.As(err, &d) {
+		d = &Diagnostic{Msg: err.Error()}
+	}
+
+	*l = append(*l, d)
+}
+
+func (l *Diagnostics) warnf(pi *packages.Package, e ast.Node, format string, args ...any) {
+	d := newDiagnostic(pi, e, format, args...)
+	d.Severity = SeverityWarning
+
+	*l = append(*l, d)
+}
+
+func newDiagnostic(pi *packages.Package, e ast.Node, format string, args ...any) *Diagnostic {
+	d := &Diagnostic{Msg: fmt.Sprintf(format, args...)}
+
+	if pi != nil && e != nil {
+		d.Pos = pi.Fset.Position(e.Pos())
+
+		var buf bytes.Buffer
+
+		cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 4}
+		_ = cfg.Fprint(&buf, pi.Fset, e)
+
+		d.Expr = buf.String()
+	}
+
+	return d
+}
+
+// errorf aborts translation of the current function.
+// The error is converted to the diagnostic by the catch.
+func errorf(pi *packages.Package, e ast.Node, format string, args ...any) {
+	panic(newDiagnostic(pi, e, format, args...))
+}
+
+// catch recovers from the error raised by errorf or util.Fatal
+// while translating function "name" and returns it in err.
+// Function declaration position is reported if the error
+// doesn't have one.
+func catch(pi *packages.Package, name string, n ast.Node, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	var d *Diagnostic
+
+	switch e := r.(type) {
+	case *Diagnostic:
+		d = e
+	case *util.Error:
+		d = &Diagnostic{Msg: e.Msg}
+	default:
+		panic(r)
+	}
+
+	if d.Func == "" {
+		d.Func = name
+	}
+
+	if !d.Pos.IsValid() && pi != nil && n != nil {
+		d.Pos = pi.Fset.Position(n.Pos())
+	}
+
+	*err = d
+}
+
+// packageErrors converts package loading errors to the diagnostics.
+func packageErrors(pkgs []*packages.Package) Diagnostics {
+	var l Diagnostics
+
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, e := range pkg.Errors {
+			l = append(l, &Diagnostic{Pos: parsePosition(e.Pos), Msg: e.Msg})
+		}
+	})
+
+	return l
+}
+
+// parsePosition parses position in the "file:line:column" form.
+func parsePosition(s string) token.Position {
+	var pos token.Position
+
+	for i := 0; i < 2; i++ {
+		n := strings.LastIndexByte(s, ':')
+		if n < 0 {
+			break
+		}
+
+		v, err := strconv.Atoi(s[n+1:])
+		if err != nil {
+			break
+		}
+
+		pos.Column, pos.Line = pos.Line, v
+		s = s[:n]
+	}
+
+	pos.Filename = s
+
+	return pos
+}
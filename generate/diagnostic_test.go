@@ -0,0 +1,84 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePosition(t *testing.T) {
+	cases := []struct {
+		in  string
+		exp token.Position
+	}{
+		{"/a/b.go:10:5", token.Position{Filename: "/a/b.go", Line: 10, Column: 5}},
+		{"/a/b.go:10", token.Position{Filename: "/a/b.go", Line: 10}},
+		{"/a/b.go", token.Position{Filename: "/a/b.go"}},
+		{"", token.Position{}},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.exp, parsePosition(c.in), c.in)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	var l Diagnostics
+
+	require.NoError(t, l.Err())
+
+	l = append(l, &Diagnostic{
+		Severity: SeverityWarning, Pos: token.Position{Filename: "a.go", Line: 1, Column: 2},
+		Msg: "not a filter function", Expr: "x",
+	})
+
+	require.NoError(t, l.Err())
+
+	l = append(l, &Diagnostic{
+		Pos: token.Position{Filename: "a.go", Line: 3, Column: 4},
+		Msg: "unsupported operand type", Expr: "d.F + 1", Func: "pkg.Filter",
+	})
+
+	err := l.Err()
+	require.Error(t, err)
+	assert.Equal(t, "a.go:1:2: warning: not a filter function: x\n"+
+		"a.go:3:4: error: unsupported operand type: d.F + 1 (in pkg.Filter)", err.Error())
+}
+
+func TestCatch(t *testing.T) {
+	fn := func(msg string) (err error) {
+		defer catch(nil, "pkg.Func", nil, &err)
+
+		if msg != "" {
+			errorf(nil, nil, "%s", msg)
+		}
+
+		return nil
+	}
+
+	require.NoError(t, fn(""))
+
+	err := fn("first")
+
+	var d *Diagnostic
+
+	require.ErrorAs(t, err, &d)
+	assert.Equal(t, "first", d.Msg)
+	assert.Equal(t, "pkg.Func", d.Func)
+}
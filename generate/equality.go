@@ -0,0 +1,184 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strings"
+
+	"github.com/tigrisdata/tigrisgen/expr"
+)
+
+// parseStructCmp expands equality of the struct or array values
+// to the conjunction of the equalities of their fields and elements:
+//
+//	d.Address == args.Address
+//
+// becomes
+//
+//	{"$and":[{"address.street":...},{"address.city":...}]}
+//
+// Inequality is the negation of the conjunction.
+func (f *funcParser) parseStructCmp(e *ast.BinaryExpr) (expr.Expr, bool) {
+	if e.Op != token.EQL && e.Op != token.NEQ {
+		return expr.Expr{}, false
+	}
+
+	tp := f.pi.TypesInfo.TypeOf(e.X)
+	if !isComposite(tp) {
+		return expr.Expr{}, false
+	}
+
+	x := f.parseOperand(e.X)
+	y := f.parseOperand(e.Y)
+	f.validateOperands(x, y, e)
+
+	if x.Type == expr.Constant || y.Type == expr.Constant {
+		errorf(f.pi, e, "comparison of %v with constant is not supported", tp)
+	}
+
+	eqs := f.compositeEq(e, tp, x, y)
+	if len(eqs) == 0 {
+		errorf(f.pi, e, "comparison of %v without fields stored in the document is not supported", tp)
+	}
+
+	c := expr.And(eqs...)
+	if e.Op == token.NEQ {
+		return expr.Negate(c), true
+	}
+
+	return c, true
+}
+
+// isComposite reports whether the values of the type are compared
+// field by field or element by element, rather than as a whole.
+// Types with custom marshaling, like time.Time or uuid.UUID,
+// are compared as a whole.
+func isComposite(tp types.Type) bool {
+	if tp == nil || customMarshaler(tp) != "" {
+		return false
+	}
+
+	switch tp.Underlying().(type) {
+	case *types.Struct, *types.Array:
+		return true
+	}
+
+	return false
+}
+
+// compositeEq returns the equalities of the fields and elements
+// of the values of the type, recursing into nested structs and arrays.
+// Unexported fields and the fields skipped by the JSON marshaling
+// are not stored in the document, so they are not compared.
+// The zero values of the fields with omitempty are not stored either,
+// so such fields can't be compared by value and are reported.
+func (f *funcParser) compositeEq(e ast.Node, tp types.Type, x expr.Operand, y expr.Operand) []expr.Expr {
+	if !isComposite(tp) {
+		return []expr.Expr{filterOp(expr.Eq, x, y)}
+	}
+
+	var res []expr.Expr
+
+	switch t := tp.Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if !t.Field(i).Exported() || jsonTag(t, i) == "-" {
+				continue
+			}
+
+			if omitsEmpty(t, i) {
+				errorf(f.pi, e, "comparison of the field '%v' with omitempty is not supported", t.Field(i).Name())
+			}
+
+			res = append(res, f.compositeEq(e, t.Field(i).Type(), fieldOperand(x, t, i), fieldOperand(y, t, i))...)
+		}
+	case *types.Array:
+		for i := int64(0); i < t.Len(); i++ {
+			res = append(res, f.compositeEq(e, t.Elem(), elemOperand(x, i), elemOperand(y, i))...)
+		}
+	}
+
+	return res
+}
+
+// fieldOperand returns the operand of the i-th field of the struct operand.
+// Fields of the embedded structs are promoted to the parent the same way
+// as the JSON marshaling does.
+func fieldOperand(x expr.Operand, t *types.Struct, i int) expr.Operand {
+	fld := t.Field(i)
+
+	switch {
+	case x.Type == expr.Field:
+		if _, ok := fld.Type().Underlying().(*types.Struct); !ok || !fld.Embedded() || jsonTag(t, i) != "" {
+			x.Value = joinPath(x.Value.(string), toFieldName(t, []string{fld.Name()}))
+		}
+	case x.Pipeline != "":
+		x.Pipeline = x.ArgPath() + "." + fld.Name()
+	default:
+		x.Value = joinPath(x.Value.(string), fld.Name())
+	}
+
+	return x
+}
+
+// elemOperand returns the operand of the i-th element of the array operand.
+func elemOperand(x expr.Operand, i int64) expr.Operand {
+	if x.Type == expr.Field {
+		x.Value = joinPath(x.Value.(string), fmt.Sprintf("%d", i))
+		return x
+	}
+
+	return expr.NewPipeline(fmt.Sprintf("index %v %d", x.ArgPath(), i))
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+// jsonTag returns the name from the JSON tag of the i-th field of the struct.
+func jsonTag(t *types.Struct, i int) string {
+	return strings.Split(reflect.StructTag(t.Tag(i)).Get("json"), ",")[0]
+}
+
+// omitsEmpty reports whether the i-th field of the struct is omitted
+// by the JSON marshaling, when it has the zero value.
+// Structs and non-empty arrays are never omitted.
+func omitsEmpty(t *types.Struct, i int) bool {
+	for _, opt := range strings.Split(reflect.StructTag(t.Tag(i)).Get("json"), ",")[1:] {
+		if opt != "omitempty" {
+			continue
+		}
+
+		switch u := t.Field(i).Type().Underlying().(type) {
+		case *types.Struct:
+			return false
+		case *types.Array:
+			return u.Len() == 0
+		}
+
+		return true
+	}
+
+	return false
+}
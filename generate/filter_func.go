@@ -16,9 +16,9 @@ package generate
 
 import (
 	"go/ast"
+	"go/token"
 	"go/types"
 
-	"github.com/rs/zerolog/log"
 	"github.com/tigrisdata/tigrisgen/expr"
 	filter "github.com/tigrisdata/tigrisgen/marshal/tigris"
 	"github.com/tigrisdata/tigrisgen/util"
@@ -26,18 +26,27 @@ import (
 )
 
 func (f *funcParser) parseIfStatement(stmt *ast.IfStmt) (expr.Expr, *expr.Expr) {
-	log.Debug().Msg("parseIfStatement")
+	f.log.Debug().Msg("parseIfStatement")
 
 	if stmt.Init != nil {
-		util.Fatal("if init section is not supported")
+		f.parseIfInit(stmt.Init)
 	}
 
+	return f.parseIfBranches(stmt, f.parseIfCond(stmt.Cond))
+}
+
+func (f *funcParser) parseIfCond(cond ast.Expr) expr.Expr {
 	var ifCond expr.Expr
 
-	switch e := stmt.Cond.(type) {
+	switch e := cond.(type) {
 	case *ast.BinaryExpr:
 		ifCond = f.parseBinaryExpr(e)
 	case *ast.Ident: // if true/false/bool field/bool arg {}
+		if f.isLocal(e) {
+			ifCond = f.parseBinaryExprLow(e)
+			break
+		}
+
 		x := f.parseOperand(e)
 		if x.Type == expr.Field {
 			ifCond = expr.NewExpr(expr.Eq, x, expr.NewConstant(true))
@@ -46,7 +55,7 @@ func (f *funcParser) parseIfStatement(stmt *ast.IfStmt) (expr.Expr, *expr.Expr)
 		} else if x.Type == expr.Constant {
 			b, ok := x.Value.(bool)
 			if !ok {
-				FatalWithExpr(f.pi, e, "unsupported constant in if")
+				errorf(f.pi, e, "unsupported constant in if")
 			}
 			if b {
 				ifCond = expr.True
@@ -61,14 +70,22 @@ func (f *funcParser) parseIfStatement(stmt *ast.IfStmt) (expr.Expr, *expr.Expr)
 		} else if x.Type == expr.Arg {
 			ifCond = expr.NewExpr(expr.Eq, x, expr.NewConstant(true)).Client()
 		} else {
-			FatalWithExpr(f.pi, e, "unsupported select in if condition")
+			errorf(f.pi, e, "unsupported select in if condition")
 		}
 	case *ast.UnaryExpr:
 		ifCond = f.parseUnaryNegation(e.X)
+	case *ast.ParenExpr, *ast.CallExpr:
+		ifCond = f.parseBinaryExprLow(e)
 	default:
-		FatalWithExpr(f.pi, e, "unsupported statement if statement")
+		errorf(f.pi, e, "unsupported statement if statement")
 	}
 
+	return ifCond
+}
+
+// parseIfBranches translates the body and else branches of the if statement
+// with already parsed condition.
+func (f *funcParser) parseIfBranches(stmt *ast.IfStmt, ifCond expr.Expr) (expr.Expr, *expr.Expr) {
 	ifBody, ifBodyFallThrough := f.parseBlockStmt(stmt.Body)
 
 	ifExpr := expr.And(ifCond, ifBody)
@@ -120,25 +137,153 @@ func (f *funcParser) parseIfStatement(stmt *ast.IfStmt) (expr.Expr, *expr.Expr)
 	return ifExpr, &b
 }
 
+// caseCond builds the condition of the switch case clause:
+// tag == v1 || tag == v2 ... or v1 || v2 ... for the tagless switch.
+func caseCond(tag ast.Expr, c *ast.CaseClause) ast.Expr {
+	var cond ast.Expr
+
+	for _, v := range c.List {
+		if tag != nil {
+			v = &ast.BinaryExpr{X: tag, OpPos: v.Pos(), Op: token.EQL, Y: v}
+		}
+
+		if cond == nil {
+			cond = v
+		} else {
+			cond = &ast.BinaryExpr{X: cond, OpPos: v.Pos(), Op: token.LOR, Y: v}
+		}
+	}
+
+	return cond
+}
+
+// checkNoBreak reports break statements which refer to the switch.
+func (f *funcParser) checkNoBreak(body []ast.Stmt) {
+	for _, v := range body {
+		ast.Inspect(v, func(n ast.Node) bool {
+			switch e := n.(type) {
+			case *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt, *ast.ForStmt, *ast.RangeStmt, *ast.FuncLit:
+				return false
+			case *ast.BranchStmt:
+				if e.Tok == token.BREAK {
+					errorf(f.pi, e, "break in switch is not supported")
+				}
+			}
+
+			return true
+		})
+	}
+}
+
+// switchToIf lowers the switch statement to the chain of if statements,
+// so as it's translated the same way as if statements.
+// Fallthrough is resolved by appending the body of the next clause,
+// default clause becomes the last else branch.
+func (f *funcParser) switchToIf(stmt *ast.SwitchStmt) []ast.Stmt {
+	if stmt.Init != nil {
+		errorf(f.pi, stmt.Init, "switch init section is not supported")
+	}
+
+	clauses := make([]*ast.CaseClause, 0, len(stmt.Body.List))
+
+	for _, v := range stmt.Body.List {
+		clauses = append(clauses, v.(*ast.CaseClause))
+	}
+
+	bodies := make([][]ast.Stmt, len(clauses))
+
+	for i := len(clauses) - 1; i >= 0; i-- {
+		body := clauses[i].Body
+
+		if n := len(body); n > 0 {
+			if br, ok := body[n-1].(*ast.BranchStmt); ok && br.Tok == token.FALLTHROUGH {
+				if i == len(clauses)-1 {
+					errorf(f.pi, br, "cannot fallthrough final case in switch")
+				}
+
+				body = append(body[:n-1:n-1], bodies[i+1]...)
+			}
+		}
+
+		f.checkNoBreak(body)
+
+		bodies[i] = body
+	}
+
+	var (
+		first, last *ast.IfStmt
+		def         *ast.BlockStmt
+	)
+
+	for i, c := range clauses {
+		body := &ast.BlockStmt{Lbrace: c.Colon, List: bodies[i], Rbrace: c.End()}
+
+		if c.List == nil {
+			def = body
+			continue
+		}
+
+		ifs := &ast.IfStmt{If: c.Case, Cond: caseCond(stmt.Tag, c), Body: body}
+
+		if first == nil {
+			first = ifs
+		} else {
+			last.Else = ifs
+		}
+
+		last = ifs
+	}
+
+	switch {
+	case first == nil && def == nil:
+		return nil
+	case first == nil:
+		return def.List
+	case def != nil:
+		last.Else = def
+	}
+
+	return []ast.Stmt{first}
+}
+
 func (f *funcParser) parseBlockStmtLow(block []ast.Stmt) (expr.Expr, *expr.Expr) {
-	log.Debug().Msg("parseBlockStatement")
+	f.log.Debug().Msg("parseBlockStatement")
 
 	i := 0
 	for ; i < len(block); i++ {
 		v := block[i]
 
 		switch e := v.(type) {
+		case *ast.AssignStmt:
+			if e.Tok != token.DEFINE {
+				errorf(f.pi, e, "unsupported assignment, only local variable definitions are allowed")
+			}
+
+			f.parseAssignLocals(e)
+		case *ast.DeclStmt:
+			f.parseDeclLocals(e)
+		case *ast.SwitchStmt:
+			return f.parseBlockStmtLow(append(f.switchToIf(e), block[i+1:]...))
 		case *ast.ReturnStmt:
 			if i < len(block)-1 {
-				FatalWithExpr(f.pi, block[i+1], "unreachable code")
+				errorf(f.pi, block[i+1], "unreachable code")
 			}
 
 			return f.parseReturnStatement(e), nil
-		case *ast.IfStmt:
-			ifExpr, ifFallThrough := f.parseIfStatement(e)
+		case *ast.IfStmt, *ast.RangeStmt:
+			var (
+				ifExpr        expr.Expr
+				ifFallThrough *expr.Expr
+			)
+
+			if ifs, ok := e.(*ast.IfStmt); ok {
+				ifExpr, ifFallThrough = f.parseIfStatement(ifs)
+			} else {
+				ifExpr, ifFallThrough = f.parseRangeStmt(e.(*ast.RangeStmt))
+			}
 
 			if ifFallThrough == nil && i < len(block)-1 {
-				FatalWithExpr(f.pi, block[i+1], "unreachable code")
+				errorf(f.pi, block[i+1], "unreachable code")
 			}
 
 			if ifFallThrough == nil {
@@ -160,13 +305,14 @@ func (f *funcParser) parseBlockStmtLow(block []ast.Stmt) (expr.Expr, *expr.Expr)
 
 			return blockExpr, nil
 		default:
-			FatalWithExpr(f.pi, e, "unsupported block statement")
+			errorf(f.pi, e, "unsupported block statement")
 		}
 	}
 
-	FatalWithExpr(f.pi, nil, "unsupported block statement")
+	// control reaches the end of the block
+	t := expr.True
 
-	return expr.Expr{}, &expr.Expr{}
+	return expr.False, &t
 }
 
 func (f *funcParser) parseBlockStmt(block *ast.BlockStmt) (expr.Expr, *expr.Expr) {
@@ -181,20 +327,22 @@ func argToType(expr ast.Expr, mustBeStruct bool, pi *packages.Package) *types.St
 	}
 
 	if t == nil {
-		FatalWithExpr(pi, expr, "Argument type not found")
+		errorf(pi, expr, "Argument type not found")
 	}
 
 	s, ok := t.Underlying().(*types.Struct)
 	if !ok && mustBeStruct {
-		FatalWithExpr(pi, expr, "Document parameter should be of struct type, got:")
+		errorf(pi, expr, "Document parameter should be of struct type, got:")
 	}
 
 	return s
 }
 
-// returns filter name and filter body parsed from function declaration.
-func parseFilterFunction(name string, fn *ast.FuncDecl, pi *packages.Package) (string, string) {
-	log.Debug().Str("name", name).Msg("parsing filter function")
+// returns the filter parsed from function declaration.
+func (g *generator) parseFilterFunction(name string, fn *ast.FuncDecl, pi *packages.Package) (_ FilterDef, err error) {
+	defer catch(pi, name, fn, &err)
+
+	g.log.Debug().Str("name", name).Msg("parsing filter function")
 
 	if fn.Type.Results == nil || len(fn.Type.Results.List) != 1 || fn.Type.Results.List[0].Type.(*ast.Ident).Name != "bool" {
 		util.Fatal("filter should have bool return type")
@@ -231,11 +379,14 @@ func parseFilterFunction(name string, fn *ast.FuncDecl, pi *packages.Package) (s
 		arg1type = argToType(l[1].Type, false, pi)
 	}
 
-	log.Debug().Str("doc", arg0).Str("args", arg1).Msg("params")
+	g.log.Debug().Str("doc", arg0).Str("args", arg1).Msg("params")
 
-	f := funcParser{doc: arg0, args: arg1, docType: arg0type, argsType: arg1type, pi: pi}
+	f := funcParser{
+		generator: g, doc: arg0, args: arg1, docType: arg0type, argsType: arg1type, pi: pi, fn: fn,
+		captured: newCaptures(),
+	}
 
 	flt, _ := f.parseBlockStmt(fn.Body)
 
-	return name, filter.MarshalFilter(flt)
+	return FilterDef{Name: name, Body: filter.MarshalFilter(flt), Captured: f.captured.names}, nil
 }
@@ -0,0 +1,41 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+
+package generate
+
+import "slices"
+
+// Filter:
+//
+//	{"$and":[
+//		{"field_int":{"$in":{{toJSONList .Arg.ArgInts}}}},
+//		{"field_string":{"$nin":["a","b"]}}
+//	]}
+func parseTest_slices_contains(d *Doc, args Args) bool {
+	return slices.Contains(args.ArgInts, d.FieldInt) && !slices.Contains([]string{"a", "b"}, d.FieldString)
+}
+
+// Error:
+//
+//	document field is expected as the searched value: slices.Contains(args.ArgInts, 1)
+func parseTestNegative_slices_contains_arg(_ *Doc, args Args) bool {
+	return slices.Contains(args.ArgInts, 1)
+}
+
+var (
+	_ = parseTest_slices_contains
+	_ = parseTestNegative_slices_contains_arg
+)
@@ -19,8 +19,9 @@ import (
 	"context"
 	"fmt"
 	"go/ast"
-	"go/printer"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -31,12 +32,38 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/tigrisdata/tigrisgen/test"
 	"github.com/tigrisdata/tigrisgen/util"
-	"golang.org/x/tools/go/packages"
 )
 
 const (
 	testConstInt    = 10 + 18
 	testConstString = "aaa" + "bbb"
+
+	testAPIPackage = "github.com/tigrisdata/tigrisgen/test"
+)
+
+// testGen is used to parse test functions.
+var testGen *generator
+
+type Status string
+
+const (
+	StatusActive  Status = "active"
+	StatusDeleted Status = "deleted"
+)
+
+type Level int
+
+type Color int
+
+const ColorRed Color = 1
+
+func (c Color) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("color%d", c)), nil
+}
+
+const (
+	LevelLow Level = iota + 1
+	LevelHigh
 )
 
 type Nested struct {
@@ -57,6 +84,30 @@ type Nested struct {
 	FieldMapStruct map[string]Nested
 }
 
+type Geo struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+type Address struct {
+	Street string `json:"street"`
+	City   string
+	Geo    Geo       `json:"geo"`
+	Lines  [2]string `json:"lines"`
+	Since  time.Time `json:"since"`
+	Notes  string    `json:"-"`
+}
+
+type Tags struct {
+	Geo   Geo       `json:"geo,omitempty"`
+	Names [2]string `json:"names,omitempty"`
+	Label string    `json:"label,omitempty"`
+}
+
+type Marker struct {
+	Notes string `json:"-"`
+}
+
 type NestedArg struct {
 	ArgInt    int
 	ArgFloat  float64
@@ -83,17 +134,47 @@ type Doc struct {
 	FieldMapInt    map[int]string
 	FieldMapStruct map[string]Nested
 
-	Nested Nested `json:"nested"`
+	FieldPtr       *string    `json:"field_ptr"`
+	FieldPtrTime   *time.Time `json:"field_ptr_time"`
+	FieldPtrNested *Nested    `json:"field_ptr_nested"`
+
+	FieldStatus  Status  `json:"field_status"`
+	FieldLevel   Level   `json:"field_level"`
+	FieldInt64   int64   `json:"field_int64"`
+	FieldInt8    int8    `json:"field_int8"`
+	FieldUint32  uint32  `json:"field_uint32"`
+	FieldUint64  uint64  `json:"field_uint64"`
+	FieldFloat32 float32 `json:"field_float32"`
+	FieldColor   Color   `json:"field_color"`
+
+	Nested  Nested  `json:"nested"`
+	Address Address `json:"address"`
+	Tags    Tags    `json:"tags"`
+	Marker  Marker  `json:"marker"`
 }
 
 type Args struct {
-	ArgInt    int
-	ArgFloat  float64
-	ArgString string
-	ArgBool   bool
-	ArgTime   time.Time
-	ArgUUID   uuid.UUID
-	ArgBytes  []byte
+	ArgInt        int
+	ArgFloat      float64
+	ArgString     string
+	ArgBool       bool
+	ArgTime       time.Time
+	ArgUUID       uuid.UUID
+	ArgBytes      []byte
+	ArgMap        map[string]int
+	ArgAny        any
+	ArgInts       []int
+	ArgInt64      int64
+	ArgInt32      int32
+	ArgUint64     uint64
+	ArgStatus     Status
+	ArgWindow     time.Duration
+	ArgPtrStr     *string
+	ArgPtrInt     *int
+	ArgAddress    Address
+	ArgPtrAddress *Address
+	ArgTags       Tags
+	ArgMarker     Marker
 
 	NestedArg NestedArg
 }
@@ -106,6 +187,10 @@ func (d Doc) FilterOne(args float64) bool {
 	return d.FieldInt != 10 && d.FieldFloat > 122
 }
 
+func FilterConfigured(d Doc, a Args) bool {
+	return d.FieldString == a.ArgString
+}
+
 func UpdateOne(d Doc, a Args) {
 	d.FieldInt += a.ArgInt
 }
@@ -119,12 +204,14 @@ func TestAPILookup(t *testing.T) {
 	s, _ := os.Getwd()
 	log.Debug().Strs("args", os.Args).Str("pwd", s).Msg("Starting")
 
-	tigrisPkg = "github.com/tigrisdata/tigrisgen/test"
+	g := newGenerator(context.Background(), &Config{APIPackage: testAPIPackage, Log: &log.Logger})
 
-	loadProgram(Program, []string{"."})
+	_, err := g.loadProgram([]string{"."})
+	require.NoError(t, err)
 
-	for _, pi := range Program {
-		f, u := findAndParse(pi)
+	for _, pi := range g.program {
+		f, u, diags := g.findAndParse(pi)
+		require.Empty(t, diags)
 
 		if len(f) == 0 && len(u) == 0 {
 			continue
@@ -132,7 +219,7 @@ func TestAPILookup(t *testing.T) {
 
 		for _, v := range f {
 			switch v.Name {
-			case "generate.FilterOne":
+			case "github.com/tigrisdata/tigrisgen/generate.FilterOne":
 				assert.Equal(t, `{"$or":[{"$and":[{"field_int":{"$ne":10}},{"field_float":{"$gt":100}}]},{"field_float":{{toJSON .Arg}}}]}`,
 					v.Body)
 			case "github.com/tigrisdata/tigrisgen/test.FilterOne":
@@ -151,7 +238,7 @@ func TestAPILookup(t *testing.T) {
 
 		for _, v := range u {
 			switch v.Name {
-			case "generate.UpdateOne":
+			case "github.com/tigrisdata/tigrisgen/generate.UpdateOne":
 				assert.Equal(t, `{"$increment":{"field_int":{{toJSON .Arg.ArgInt}}}}`, v.Body)
 			case "github.com/tigrisdata/tigrisgen/test.UpdateOne":
 				assert.Equal(t, `{"$increment":{"Field1":{{toJSON .Arg}}}}`, v.Body)
@@ -201,6 +288,9 @@ func UpdateAPICalls() {
 
 	_, _ = test.Delete(ctx, c1, FilterOne, 1.24)
 	_, _ = test.DeleteOne(ctx, c1, FilterOne, 1.24)
+
+	// Detected only when configured, see TestAPILookupConfigured
+	_, _ = test.Find(FilterConfigured, Args{}, c1)
 }
 
 // fix `unused` lint so as functions are only parsed by the tests.
@@ -223,6 +313,73 @@ var (
 	_ = parseTest_func
 	_ = parseTest_or_true
 	_ = parseTest_or_true_nested
+	_ = parseTest_local_vars
+	_ = parseTest_local_vars_in_block
+	_ = parseTest_if_init_map
+	_ = parseTest_if_init_type_assert
+	_ = parseTest_if_init
+	_ = parseTestNegative_if_init
+	_ = parseTest_string_prefix
+	_ = parseTest_regexp
+	_ = parseTest_regexp_negate
+	_ = parseTestNegative_regexp_invalid
+	_ = parseTestNegative_prefix_of_arg
+	_ = parseTest_range_in
+	_ = parseTest_range_nin
+	_ = parseTestNegative_range_cond
+	_ = parseTestNegative_range_var_in_body
+	_ = parseTest_elem_match
+	_ = parseTest_elem_match_negate
+	_ = parseTestNegative_elem_match_doc
+	_ = parseTest_nil_checks
+	_ = parseTest_pointer_deref
+	_ = parseTest_map_presence
+	_ = parseTest_len_array
+	_ = parseTest_len_array_le_arg
+	_ = parseTest_len_array_ge_lt_arg
+	_ = parseTest_len_string
+	_ = parseTest_len_map
+	_ = parseTestNegative_len_map
+	_ = parseTestNegative_len_map_arg
+	_ = parseTestNegative_len_string
+	_ = parseTestNegative_len_arg_eq
+	_ = parseTest_conversions
+	_ = parseTest_typed_constants
+	_ = parseTestNegative_conversion_truncate
+	_ = parseTestNegative_conversion_narrow
+	_ = parseTestNegative_conversion_int_float
+	_ = parseTestNegative_conversion_int_float32
+	_ = parseTestNegative_conversion_marshaler
+	_ = parseTestNegative_const_marshaler
+	_ = parseTest_fold_pure
+	_ = parseTestNegative_fold_invalid
+	_ = parseTestNegative_fold_not_const
+	_ = parseTestNegative_fold_local_time
+	_ = parseTest_time_now
+	_ = parseTest_time_since
+	_ = parseTestNegative_time_field_arithmetic
+	_ = parseTest_arith_args
+	_ = parseTest_arith_conversion
+	_ = parseTest_concat_args
+	_ = parseTestNegative_arith_field
+	_ = parseTest_arg_call
+	_ = parseTestNegative_arg_call_field
+	_ = parseTest_arg_call_conversion
+	_ = parseTestNegative_arg_call_conversion
+	_ = parseTest_inline_func
+	_ = parseTest_inline_method
+	_ = parseTest_inline_addr
+	_ = parseTest_inline_params
+	_ = parseTest_inline_package
+	_ = parseTest_inline_field
+	_ = parseTestNegative_inline_recursive
+	_ = parseTestNegative_inline_stdlib
+	_ = parseTest_struct_eq
+	_ = parseTest_struct_ne
+	_ = parseTest_bytes_equal
+	_ = parseTestNegative_struct_literal
+	_ = parseTestNegative_struct_omitempty
+	_ = parseTestNegative_struct_empty
 )
 
 // Filter:
@@ -368,6 +525,728 @@ func parseTest_or_true_nested(d *Doc, _ Args) bool {
 	return d.FieldInt == 1 && (true || d.FieldInt != 1 || false)
 }
 
+// Filter:
+//
+//	{"$or":[
+//		{"$and":[{"field_bool":true},{"field_int":{"$gt":10}}]},
+//		{"$and":[{"field_string":{{toJSON .Arg.ArgString}}},{"field_float":{{toJSON .Arg.NestedArg.ArgFloat}}}]}
+//	]}
+func parseTest_local_vars(d *Doc, args Args) bool {
+	const limit = 10
+
+	name := args.ArgString
+	nested := args.NestedArg
+
+	var active = d.FieldBool && d.FieldInt > limit
+
+	return active || d.FieldString == name && d.FieldFloat == nested.ArgFloat
+}
+
+// Filter:
+//
+//	{{ if or ( eq .Arg.ArgBool true ) ( ne .Arg.ArgBool true ) }}
+//	{"$or":[
+//		{{ if eq .Arg.ArgBool true }}
+//			{"field_int":{"$gt":{{toJSON .Arg.ArgInt}}}}
+//		{{end}}
+//		{{ if ne .Arg.ArgBool true }}
+//			{{ if eq .Arg.ArgBool true }},{{end}}
+//			{"field_bool":{"$ne":true}}
+//		{{end}}
+//	]}
+//	{{end}}
+func parseTest_local_vars_in_block(d *Doc, args Args) bool {
+	if args.ArgBool {
+		min := args.ArgInt
+		return d.FieldInt > min
+	}
+
+	isSet := d.FieldBool
+
+	return !isSet
+}
+
+// Filter:
+//
+//	{{ if or ( eq (hasKey .Arg.ArgMap "limit") true ) ( ne (hasKey .Arg.ArgMap "limit") true ) }}
+//	{"$or":[
+//		{{ if eq (hasKey .Arg.ArgMap "limit") true }}
+//			{"field_int":{"$gt":{{toJSON .Arg.ArgMap.limit}}}}
+//		{{end}}
+//		{{ if ne (hasKey .Arg.ArgMap "limit") true }}
+//			{{ if eq (hasKey .Arg.ArgMap "limit") true }},{{end}}
+//			{"field_int":{"$gt":{{toJSON .Arg.ArgInt}}}}
+//		{{end}}
+//	]}
+//	{{end}}
+func parseTest_if_init_map(d *Doc, args Args) bool {
+	if v, ok := args.ArgMap["limit"]; ok {
+		return d.FieldInt > v
+	}
+
+	return d.FieldInt > args.ArgInt
+}
+
+// Filter:
+//
+//	{{ if and ( eq (isType .Arg.ArgAny "string") true ) ( ne .Arg.ArgAny "" ) }}
+//		{"field_string":{{toJSON .Arg.ArgAny}}}
+//	{{end}}
+func parseTest_if_init_type_assert(d *Doc, args Args) bool {
+	if s, ok := args.ArgAny.(string); ok && s != "" {
+		return d.FieldString == s
+	}
+
+	return false
+}
+
+// Filter:
+//
+//	{{ if or ( gt .Arg.ArgInt 1 ) ( le .Arg.ArgInt 1 ) }}
+//	{"$or":[
+//		{{ if gt .Arg.ArgInt 1 }}
+//			{"field_int":{{toJSON .Arg.ArgInt}}}
+//		{{end}}
+//		{{ if le .Arg.ArgInt 1 }}
+//			{{ if gt .Arg.ArgInt 1 }},{{end}}
+//			{"field_int":{"$gt":0}}
+//		{{end}}
+//	]}
+//	{{end}}
+func parseTest_if_init(d *Doc, args Args) bool {
+	if v := args.ArgInt; v > 1 {
+		return d.FieldInt == v
+	}
+
+	return d.FieldInt > 0
+}
+
+// Error:
+//
+//	unsupported if init statement: args.ArgInt++
+func parseTestNegative_if_init(d *Doc, args Args) bool {
+	if args.ArgInt++; d.FieldInt > 1 {
+		return true
+	}
+
+	return false
+}
+
+var testRegexp = regexp.MustCompile(`^[a-z]+\.com$`)
+
+// Filter:
+//
+//	{"$and":[
+//		{"field_string":{"$regex":"^a\\.b"}},
+//		{"field_string":{"$regex":{{toJSON (print (quoteMeta .Arg.ArgString) "$")}}}},
+//		{"field_string":{"$not_regex":"^x\\*"}}
+//	]}
+func parseTest_string_prefix(d *Doc, args Args) bool {
+	return strings.HasPrefix(d.FieldString, "a.b") && strings.HasSuffix(d.FieldString, args.ArgString) &&
+		!strings.HasPrefix(d.FieldString, "x*")
+}
+
+// Filter:
+//
+//	{"$or":[
+//		{"field_string":{"$regex":"^[0-9]+$"}},
+//		{"field_string":{"$regex":"^[a-z]+\\.com$"}},
+//		{"field_string":{"$regex":{{toJSON .Arg.ArgString}}}}
+//	]}
+func parseTest_regexp(d *Doc, args Args) bool {
+	return regexp.MustCompile("^[0-9]+$").MatchString(d.FieldString) || testRegexp.MatchString(d.FieldString) ||
+		regexp.MustCompile(args.ArgString).MatchString(d.FieldString)
+}
+
+// Filter:
+//
+//	{"$and":[
+//		{"field_string":{"$not_regex":"^[a-z]+\\.com$"}},
+//		{"field_string":{"$not_regex":{{toJSON .Arg.ArgString}}}}
+//	]}
+func parseTest_regexp_negate(d *Doc, args Args) bool {
+	re := regexp.MustCompile(args.ArgString)
+
+	return !(testRegexp.MatchString(d.FieldString) || re.MatchString(d.FieldString))
+}
+
+// Error:
+//
+//	invalid regular expression: error parsing regexp: missing closing ): `(`: "("
+func parseTestNegative_regexp_invalid(d *Doc, _ Args) bool {
+	return regexp.MustCompile("(").MatchString(d.FieldString)
+}
+
+// Error:
+//
+//	document field is expected as the matched string: strings.HasPrefix(args.ArgString, "x")
+func parseTestNegative_prefix_of_arg(_ *Doc, args Args) bool {
+	return strings.HasPrefix(args.ArgString, "x")
+}
+
+// Filter:
+//
+//	{"$or":[
+//		{"field_int":{"$in":{{toJSONList .Arg.ArgInts}}}},
+//		{"$and":[{"field_int":{"$nin":{{toJSONList .Arg.ArgInts}}}},{"field_string":"abc"}]}
+//	]}
+func parseTest_range_in(d *Doc, args Args) bool {
+	for _, v := range args.ArgInts {
+		if d.FieldInt == v {
+			return true
+		}
+	}
+
+	return d.FieldString == "abc"
+}
+
+// Filter:
+//
+//	{"$and":[{"field_int":{"$nin":[1,2,3]}},{"field_string":"abc"}]}
+func parseTest_range_nin(d *Doc, _ Args) bool {
+	for _, v := range []int{1, 2, 3} {
+		if v == d.FieldInt {
+			return false
+		}
+	}
+
+	return d.FieldString == "abc"
+}
+
+// Error:
+//
+//	only equality of the document field and the loop variable is supported: d.FieldInt > v
+func parseTestNegative_range_cond(d *Doc, args Args) bool {
+	for _, v := range args.ArgInts {
+		if d.FieldInt > v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Error:
+//
+//	loop variable is only supported in the loop condition: v
+func parseTestNegative_range_var_in_body(d *Doc, args Args) bool {
+	for _, v := range args.ArgInts {
+		if d.FieldInt == v {
+			return d.FieldFloat > float64(v)
+		}
+	}
+
+	return false
+}
+
+// Filter:
+//
+//	{"field_arr":{"$elemMatch":{"$and":[
+//		{"field_int":{"$gt":5}},
+//		{"field_string":{{toJSON .Arg.ArgString}}}
+//	]}}}
+func parseTest_elem_match(d *Doc, args Args) bool {
+	for _, e := range d.FieldArr {
+		if e.FieldInt > 5 && e.FieldString == args.ArgString {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Filter:
+//
+//	{"$and":[
+//		{"nested.field_arr":{"$not":{"$elemMatch":{"$or":[{"field_bool":true},{"field_float":{"$lt":1.5}}]}}}},
+//		{"field_int":1}
+//	]}
+func parseTest_elem_match_negate(d *Doc, _ Args) bool {
+	for _, e := range d.Nested.FieldArr {
+		if e.FieldBool || e.FieldFloat < 1.5 {
+			return false
+		}
+	}
+
+	return d.FieldInt == 1
+}
+
+// Error:
+//
+//	unsupported selector, expected: e: d.FieldInt
+func parseTestNegative_elem_match_doc(d *Doc, _ Args) bool {
+	for _, e := range d.FieldArr {
+		if e.FieldInt == d.FieldInt {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Filter:
+//
+//	{"$or":[{"field_ptr":null},{"$and":[{"FieldMap":{"$ne":null}},{"field_arr":null}]}]}
+func parseTest_nil_checks(d *Doc, _ Args) bool {
+	return d.FieldPtr == nil || d.FieldMap != nil && d.FieldArr == nil
+}
+
+// Filter:
+//
+//	{"$and":[
+//		{"field_ptr":{"$ne":null}},
+//		{"field_ptr":{{toJSON .Arg.ArgString}}},
+//		{"field_ptr_time":{"$gt":{{toJSON .Arg.ArgTime}}}},
+//		{"field_ptr_nested.field_int":{"$gt":1}},
+//		{"field_ptr_nested.field_float":{"$lt":2}}
+//	]}
+func parseTest_pointer_deref(d *Doc, args Args) bool {
+	return d.FieldPtr != nil && *d.FieldPtr == args.ArgString && d.FieldPtrTime.After(args.ArgTime) &&
+		(*d.FieldPtrNested).FieldInt > 1 && d.FieldPtrNested.FieldFloat < 2
+}
+
+// Filter:
+//
+//	{"$and":[{"FieldMapStruct.k":{"$exists":true}},{"FieldMap.key":{"$exists":true}}]}
+func parseTest_map_presence(d *Doc, _ Args) bool {
+	_, ok := d.FieldMap["key"]
+	if _, exists := d.FieldMapStruct["k"]; !exists {
+		return false
+	}
+
+	return ok
+}
+
+// Filter:
+//
+//	{{ if lt .Arg.ArgInt 0 }}
+//		{}
+//	{{else}}
+//		{"$or":[
+//			{"field_arr.0":{"$exists":false}},
+//			{"$and":[{"nested.field_arr_float.1":{"$exists":true}},{"field_arr.4":{"$exists":false}}]},
+//			{"field_arr_float.{{.Arg.ArgInt}}":{"$exists":true}}
+//		]}
+//	{{end}}
+func parseTest_len_array(d *Doc, args Args) bool {
+	return len(d.FieldArr) == 0 || len(d.Nested.FieldArrFloat) >= 2 && 5 > len(d.FieldArr) ||
+		len(d.FieldArrFloat) > args.ArgInt
+}
+
+// Filter:
+//
+//	{{ if ge .Arg.ArgInt 0 }}{"field_arr.{{.Arg.ArgInt}}":{"$exists":false}}{{end}}
+func parseTest_len_array_le_arg(d *Doc, args Args) bool {
+	return len(d.FieldArr) <= args.ArgInt
+}
+
+// Filter:
+//
+//	{{ if ge (sub .Arg.ArgInt 1) 0 }}{"$and":[
+//		{{ if lt (sub .Arg.ArgInt 1) 0 }}
+//			{}
+//		{{else}}
+//			{"field_arr.{{(sub .Arg.ArgInt 1)}}":{"$exists":true}}
+//		{{end}},
+//		{"field_arr_float.{{(sub .Arg.ArgInt 1)}}":{"$exists":false}}
+//	]}{{end}}
+func parseTest_len_array_ge_lt_arg(d *Doc, args Args) bool {
+	return len(d.FieldArr) >= args.ArgInt && len(d.FieldArrFloat) < args.ArgInt
+}
+
+// Filter:
+//
+//	{{ if gt (len .Arg.ArgInts) 0 }}{"field_string":{"$ne":""}}{{end}}
+func parseTest_len_string(d *Doc, args Args) bool {
+	return len(d.FieldString) != 0 && len(args.ArgInts) > 0
+}
+
+// Filter:
+//
+//	{"$or":[
+//		{"FieldMap":null},
+//		{"FieldMap":{}},
+//		{"$and":[
+//			{"FieldMapStruct":{"$ne":null}},
+//			{"FieldMapStruct":{"$ne":{}}},
+//			{"nested.FieldMapInt":{"$ne":null}},
+//			{"nested.FieldMapInt":{"$ne":{}}}
+//		]}
+//	]}
+func parseTest_len_map(d *Doc, _ Args) bool {
+	return len(d.FieldMap) == 0 || len(d.FieldMapStruct) != 0 && 0 < len(d.Nested.FieldMapInt)
+}
+
+// Error:
+//
+//	only comparison of the map length with zero is supported: len(d.FieldMap) > 1
+func parseTestNegative_len_map(d *Doc, _ Args) bool {
+	return len(d.FieldMap) > 1
+}
+
+// Error:
+//
+//	only comparison of the map length with zero is supported: len(d.FieldMap) >= args.ArgInt
+func parseTestNegative_len_map_arg(d *Doc, args Args) bool {
+	return len(d.FieldMap) >= args.ArgInt
+}
+
+// Error:
+//
+//	only comparison of the string length with zero is supported: len(d.FieldString) > 3
+func parseTestNegative_len_string(d *Doc, _ Args) bool {
+	return len(d.FieldString) > 3
+}
+
+// Error:
+//
+//	only ordered comparisons of the array length with the argument are supported: len(d.FieldArr) == args.ArgInt
+func parseTestNegative_len_arg_eq(d *Doc, args Args) bool {
+	return len(d.FieldArr) == args.ArgInt
+}
+
+// Filter:
+//
+//	{"$and":[
+//		{"field_uint32":{"$gt":{{toJSON .Arg.ArgFloat}}}},
+//		{"field_float":{"$gt":{{toJSON (float .Arg.ArgInt64)}}}},
+//		{"field_int":{{toJSON .Arg.ArgInt64}}},
+//		{"field_status":{{toJSON .Arg.ArgString}}},
+//		{"field_status":{{toJSON .Arg.ArgString}}},
+//		{"field_int8":{"$lt":{{toJSON .Arg.ArgInt64}}}},
+//		{"field_uint32":{"$gt":1}},
+//		{"field_float32":{"$ne":{{toJSON .Arg.ArgFloat}}}}
+//	]}
+func parseTest_conversions(d *Doc, args Args) bool {
+	return float64(d.FieldUint32) > args.ArgFloat && d.FieldFloat > float64(args.ArgInt64) &&
+		int64(d.FieldInt) == args.ArgInt64 &&
+		string(d.FieldStatus) == args.ArgString && d.FieldStatus == Status(args.ArgString) &&
+		int64(d.FieldInt8) < args.ArgInt64 && int64(d.FieldUint32) > 1 && float64(d.FieldFloat32) != args.ArgFloat
+}
+
+// Filter:
+//
+//	{"$or":[
+//		{"$and":[{"field_status":"active"},{"field_level":{"$gte":2}}]},
+//		{"field_status":{"$ne":{{toJSON .Arg.ArgStatus}}}}
+//	]}
+func parseTest_typed_constants(d *Doc, args Args) bool {
+	return d.FieldStatus == StatusActive && d.FieldLevel >= LevelHigh || d.FieldStatus != args.ArgStatus
+}
+
+// Error:
+//
+//	conversion from float64 to int can change the value: int(d.FieldFloat)
+func parseTestNegative_conversion_truncate(d *Doc, args Args) bool {
+	return int(d.FieldFloat) == args.ArgInt
+}
+
+// Error:
+//
+//	conversion from int to int8 can change the value: int8(d.FieldInt)
+func parseTestNegative_conversion_narrow(d *Doc, args Args) bool {
+	return int8(d.FieldInt) == int8(args.ArgInt)
+}
+
+// Error:
+//
+//	conversion from int64 to float64 can change the value: float64(d.FieldInt64)
+func parseTestNegative_conversion_int_float(d *Doc, args Args) bool {
+	return float64(d.FieldInt64) > args.ArgFloat
+}
+
+// Error:
+//
+//	conversion from uint32 to float32 can change the value: float32(d.FieldUint32)
+func parseTestNegative_conversion_int_float32(d *Doc, args Args) bool {
+	return float32(d.FieldUint32) < 1
+}
+
+// Error:
+//
+//	conversion from int to github.com/tigrisdata/tigrisgen/generate.Color with custom MarshalText is not supported: Color(args.ArgInt)
+func parseTestNegative_conversion_marshaler(d *Doc, args Args) bool {
+	return d.FieldColor == Color(args.ArgInt)
+}
+
+// Error:
+//
+//	constant of type github.com/tigrisdata/tigrisgen/generate.Color with custom MarshalText is not supported: ColorRed
+func parseTestNegative_const_marshaler(d *Doc, _ Args) bool {
+	return d.FieldColor == ColorRed
+}
+
+// Filter:
+//
+//	{"$and":[
+//		{"field_uuid":"8a9d3a2e-1f55-4e4e-9f3c-3a4b5c6d7e8f"},
+//		{"field_time":{"$gt":"2023-01-01T00:00:00Z"}},
+//		{"field_bytes":"eA=="},
+//		{"nested.field_uuid":{"$ne":"00000000-0000-0000-0000-000000000000"}}
+//	]}
+func parseTest_fold_pure(d *Doc, _ Args) bool {
+	return d.FieldUUID == uuid.MustParse("8a9d3a2e-1f55-4e4e-9f3c-3a4b5c6d7e8f") &&
+		d.FieldTime.After(time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)) &&
+		bytes.Compare(d.FieldBytes, []byte("x")) == 0 && d.Nested.FieldUUID != uuid.Nil
+}
+
+// Error:
+//
+//	can't evaluate the call: uuid: Parse(not-uuid): invalid UUID length: 8: uuid.MustParse("not-uuid")
+func parseTestNegative_fold_invalid(d *Doc, _ Args) bool {
+	return d.FieldUUID == uuid.MustParse("not-uuid")
+}
+
+// Error:
+//
+//	constant argument expected: args.ArgInt
+func parseTestNegative_fold_not_const(d *Doc, args Args) bool {
+	return d.FieldTime.Before(time.Date(args.ArgInt, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+// Error:
+//
+//	constant argument expected: time.Local
+func parseTestNegative_fold_local_time(d *Doc, _ Args) bool {
+	return d.FieldTime.Before(time.Date(2023, 1, 1, 0, 0, 0, 0, time.Local))
+}
+
+// Filter:
+//
+//	{"$and":[
+//		{"field_time":{"$lt":{{toJSON (now)}}}},
+//		{"nested.field_time":{"$gt":{{toJSON (timeAdd (now) -86400000000000)}}}},
+//		{"field_ptr_time":{"$lt":{{toJSON (timeAdd .Arg.ArgTime .Arg.ArgWindow)}}}},
+//		{"field_time":{"$ne":{{toJSON (timeAddDate (now) 0 -1 0)}}}}
+//	]}
+func parseTest_time_now(d *Doc, args Args) bool {
+	return d.FieldTime.Before(time.Now()) && d.Nested.FieldTime.After(time.Now().Add(-24*time.Hour)) &&
+		d.FieldPtrTime.Before(args.ArgTime.Add(args.ArgWindow)) && d.FieldTime != time.Now().AddDate(0, -1, 0)
+}
+
+// Filter:
+//
+//	{"$and":[
+//		{"field_time":{"$gt":{{toJSON (timeSub (now) 3600000000000)}}}},
+//		{"nested.field_time":{"$lte":{{toJSON (timeAdd (now) .Arg.ArgWindow)}}}}
+//	]}
+func parseTest_time_since(d *Doc, args Args) bool {
+	return time.Since(d.FieldTime) < time.Hour && args.ArgWindow >= time.Until(d.Nested.FieldTime)
+}
+
+// Error:
+//
+//	time arithmetic is only supported on arguments and time.Now(): d.FieldTime.Add(time.Hour)
+func parseTestNegative_time_field_arithmetic(d *Doc, args Args) bool {
+	return d.FieldTime.Add(time.Hour).Before(args.ArgTime)
+}
+
+// Filter:
+//
+//	{"$and":[{"field_float":{"$gt":{{toJSON (mul .Arg.ArgFloat 2)}}}},{"field_int":{"$gte":{{toJSON (add .Arg.ArgInt 1)}}}},{"field_int64":{"$lt":{{toJSON (mod (sub 0 .Arg.ArgInt64) 7)}}}}]}
+func parseTest_arith_args(d *Doc, args Args) bool {
+	return d.FieldFloat > args.ArgFloat*2 && d.FieldInt >= args.ArgInt+1 && d.FieldInt64 < -args.ArgInt64%7
+}
+
+// Filter:
+//
+//	{"$and":[{"field_float":{"$gt":{{toJSON (div (float .Arg.ArgInt32) 2)}}}},{"field_uint64":{"$lt":{{toJSON (add .Arg.ArgUint64 1)}}}}]}
+func parseTest_arith_conversion(d *Doc, args Args) bool {
+	return d.FieldFloat > float64(args.ArgInt32)/2 && d.FieldUint64 < args.ArgUint64+1
+}
+
+// Filter:
+//
+//	{"field_string":{{toJSON (add (add .Arg.ArgString " ") .Arg.NestedArg.ArgString)}}}
+func parseTest_concat_args(d *Doc, args Args) bool {
+	return d.FieldString == args.ArgString+" "+args.NestedArg.ArgString
+}
+
+// Error:
+//
+//	only arguments and constants are supported in arithmetic expressions: d.FieldInt
+func parseTestNegative_arith_field(d *Doc, args Args) bool {
+	return d.FieldInt+1 > args.ArgInt
+}
+
+// Filter:
+//
+//	{"$and":[{"field_string":{{toJSON (lower (trimSpace .Arg.ArgString))}}},{"nested.field_string":{"$ne":{{toJSON (.Arg.ArgUUID.String)}}}},{"field_int64":{"$gt":{{toJSON (.Arg.ArgTime.Unix)}}}}]}
+func parseTest_arg_call(d *Doc, args Args) bool {
+	return d.FieldString == strings.ToLower(strings.TrimSpace(args.ArgString)) &&
+		d.Nested.FieldString != args.ArgUUID.String() && d.FieldInt64 > args.ArgTime.Unix()
+}
+
+// Error:
+//
+//	only arguments and constants are supported in the ToLower call: d.FieldString
+func parseTestNegative_arg_call_field(d *Doc, args Args) bool {
+	return strings.ToLower(d.FieldString) == args.ArgString
+}
+
+// Filter:
+//
+//	{"$and":[{"field_string":{{toJSON (lower (str .Arg.ArgStatus))}}},{"nested.field_string":{"$ne":{{toJSON (itoa (int (add .Arg.ArgInt32 1)))}}}}]}
+func parseTest_arg_call_conversion(d *Doc, args Args) bool {
+	return d.FieldString == strings.ToLower(string(args.ArgStatus)) &&
+		d.Nested.FieldString != strconv.Itoa(int(args.ArgInt32)+1)
+}
+
+// Error:
+//
+//	conversion from int64 to time.Duration is not supported in the function call: time.Duration(args.ArgInt64)
+func parseTestNegative_arg_call_conversion(d *Doc, args Args) bool {
+	return d.FieldTime.After(args.ArgTime.Truncate(time.Duration(args.ArgInt64)))
+}
+
+func isActiveDoc(d *Doc) bool {
+	return d.FieldString == "active" && !d.FieldBool
+}
+
+func (d Doc) IsAdult() bool {
+	return d.FieldInt >= 18
+}
+
+func isActiveAdult(d *Doc) bool {
+	return isActiveDoc(d) && d.IsAdult()
+}
+
+func fieldInRange(d *Doc, min int, max int) bool {
+	return d.FieldInt >= min && d.FieldInt <= max
+}
+
+func nestedInRange(n *Nested, min int) bool {
+	return n.FieldInt >= min && n.FieldArr[1].FieldString != ""
+}
+
+func (n Nested) IsActive() bool {
+	return n.FieldBool && n.FieldFloat > 0
+}
+
+// Filter:
+//
+//	{"$and":[{"address.street":{{toJSON .Arg.ArgAddress.Street}}},{"address.City":{{toJSON .Arg.ArgAddress.City}}},{"address.geo.lat":{{toJSON .Arg.ArgAddress.Geo.Lat}}},{"address.geo.lon":{{toJSON .Arg.ArgAddress.Geo.Lon}}},{"address.lines.0":{{toJSON (index .Arg.ArgAddress.Lines 0)}}},{"address.lines.1":{{toJSON (index .Arg.ArgAddress.Lines 1)}}},{"address.since":{{toJSON .Arg.ArgAddress.Since}}}]}
+func parseTest_struct_eq(d *Doc, args Args) bool {
+	return d.Address == args.ArgAddress
+}
+
+// Filter:
+//
+//	{"$or":[{"address.geo.lat":{"$ne":{{toJSON .Arg.ArgAddress.Geo.Lat}}}},{"address.geo.lon":{"$ne":{{toJSON .Arg.ArgAddress.Geo.Lon}}}},{"$and":[{"address.street":{{toJSON ((deref .Arg.ArgPtrAddress).Street)}}},{"address.City":{{toJSON ((deref .Arg.ArgPtrAddress).City)}}},{"address.geo.lat":{{toJSON (((deref .Arg.ArgPtrAddress).Geo).Lat)}}},{"address.geo.lon":{{toJSON (((deref .Arg.ArgPtrAddress).Geo).Lon)}}},{"address.lines.0":{{toJSON (index ((deref .Arg.ArgPtrAddress).Lines) 0)}}},{"address.lines.1":{{toJSON (index ((deref .Arg.ArgPtrAddress).Lines) 1)}}},{"address.since":{{toJSON ((deref .Arg.ArgPtrAddress).Since)}}}]}]}
+func parseTest_struct_ne(d *Doc, args Args) bool {
+	return args.ArgAddress.Geo != d.Address.Geo || *args.ArgPtrAddress == d.Address
+}
+
+// Filter:
+//
+//	{"$and":[{"field_bytes":{{toJSON .Arg.ArgBytes}}},{"nested.field_bytes":{"$ne":"eA=="}}]}
+func parseTest_bytes_equal(d *Doc, args Args) bool {
+	return bytes.Equal(d.FieldBytes, args.ArgBytes) && !bytes.Equal([]byte("x"), d.Nested.FieldBytes)
+}
+
+// Error:
+//
+//	comparison of the field 'Label' with omitempty is not supported: d.Tags == args.ArgTags
+func parseTestNegative_struct_omitempty(d *Doc, args Args) bool {
+	return d.Tags == args.ArgTags
+}
+
+// Error:
+//
+//	comparison of github.com/tigrisdata/tigrisgen/generate.Marker without fields stored in the document is not supported: d.Marker != args.ArgMarker
+func parseTestNegative_struct_empty(d *Doc, args Args) bool {
+	return d.Marker != args.ArgMarker
+}
+
+// Error:
+//
+//	unsupported operand type: Geo{Lat: 1}
+func parseTestNegative_struct_literal(d *Doc, args Args) bool {
+	return d.Address.Geo == Geo{Lat: 1}
+}
+
+func matchString(d *Doc, a NestedArg, strict bool) bool {
+	if strict {
+		return d.FieldString == a.ArgString
+	}
+
+	return strings.HasPrefix(d.FieldString, a.ArgString)
+}
+
+func recursiveDoc(d *Doc, n int) bool {
+	return d.FieldInt > n || recursiveDoc(d, n+1)
+}
+
+// Filter:
+//
+//	{"$and":[{"field_string":"active"},{"field_bool":{"$ne":true}},{"field_float":{"$gt":{{toJSON .Arg.ArgFloat}}}}]}
+func parseTest_inline_func(d *Doc, args Args) bool {
+	return isActiveDoc(d) && d.FieldFloat > args.ArgFloat
+}
+
+// Filter:
+//
+//	{"$and":[{"field_string":"active"},{"field_bool":{"$ne":true}}]}
+func parseTest_inline_addr(d Doc, args Args) bool {
+	return isActiveDoc(&d)
+}
+
+// Filter:
+//
+//	{"$or":[{"field_int":{"$gte":18}},{"field_string":{"$ne":"active"}},{"field_bool":true},{"field_int":{"$lt":18}}]}
+func parseTest_inline_method(d *Doc, args Args) bool {
+	return d.IsAdult() || !isActiveAdult(d)
+}
+
+// Filter:
+//
+//	{{ if or ( eq .Arg.ArgBool true ) ( ne .Arg.ArgBool true ) }}
+//	{"$and":[
+//		{"field_int":{"$gte":{{toJSON .Arg.ArgInt}}}},
+//		{"field_int":{"$lte":100}},
+//		{"$or":[
+//			{{ if eq .Arg.ArgBool true }}
+//				{"field_string":{{toJSON .Arg.NestedArg.ArgString}}}
+//			{{end}}
+//			{{ if ne .Arg.ArgBool true }}
+//				{{ if eq .Arg.ArgBool true }},{{end}}
+//				{"field_string":{"$regex":{{toJSON (print "^" (quoteMeta .Arg.NestedArg.ArgString))}}}}
+//			{{end}}
+//		]}
+//	]}
+//	{{end}}
+func parseTest_inline_params(d *Doc, args Args) bool {
+	return fieldInRange(d, args.ArgInt, 100) && matchString(d, args.NestedArg, args.ArgBool)
+}
+
+// Filter:
+//
+//	{"$and":[{"Field1":{"$lt":{{toJSON .Arg.ArgInt}}}},{"nested.field_222":{"$ne":""}}]}
+func parseTest_inline_package(d *test.Doc, args Args) bool {
+	return test.IsSmall(d, args.ArgInt) && d.HasNested()
+}
+
+// Filter:
+//
+//	{"$and":[{"nested.field_int":{"$gte":{{toJSON .Arg.ArgInt}}}},{"nested.field_arr.1.field_string":{"$ne":""}},{"nested.field_bool":true},{"nested.field_float":{"$gt":0}}]}
+func parseTest_inline_field(d *Doc, args Args) bool {
+	return nestedInRange(&d.Nested, args.ArgInt) && d.Nested.IsActive()
+}
+
+// Error:
+//
+//	recursive call of recursiveDoc is not supported: recursiveDoc(d, n+1)
+func parseTestNegative_inline_recursive(d *Doc, args Args) bool {
+	return recursiveDoc(d, args.ArgInt)
+}
+
+// Error:
+//
+//	unsupported function call strings.EqualFold: strings.EqualFold(d.FieldString, args.ArgString)
+func parseTestNegative_inline_stdlib(d *Doc, args Args) bool {
+	return strings.EqualFold(d.FieldString, args.ArgString)
+}
+
 func cleanupComment(comment string) string {
 	comment = strings.ReplaceAll(comment, "\n", "")
 	comment = strings.ReplaceAll(comment, "\t", "")
@@ -400,38 +1279,25 @@ or
 	}
 }
 
-func setupFatalHandlers() {
-	FatalWithExpr = func(pi *packages.Package, e ast.Node, format string, args ...any) {
-		cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 4}
-
-		var buf bytes.Buffer
-		_ = cfg.Fprint(&buf, pi.Fset, e)
-		panic(fmt.Sprintf("%s: %s", fmt.Sprintf(format, args...), buf.String()))
-	}
+// diagnosticMessage returns error message followed by the failing expression.
+func diagnosticMessage(t *testing.T, err error) string {
+	t.Helper()
 
-	util.Fatal = func(format string, args ...any) {
-		panic(fmt.Sprintf(format, args...))
-	}
-}
+	var d *Diagnostic
 
-func catchFatalError(errMsg *string) {
-	res := recover()
-	if res != nil {
-		s, ok := res.(string)
-		if !ok {
-			panic(res)
-		}
+	require.ErrorAs(t, err, &d)
 
-		*errMsg = s
+	if d.Expr != "" {
+		return d.Msg + ": " + d.Expr
 	}
+
+	return d.Msg
 }
 
 func execTests(t *testing.T, prefix string, update bool) {
 	t.Helper()
 
-	setupFatalHandlers()
-
-	for _, pi := range Program {
+	for _, pi := range testGen.program {
 		for _, f := range pi.Syntax {
 			for _, v := range f.Decls {
 				fn, ok := v.(*ast.FuncDecl)
@@ -447,19 +1313,21 @@ func execTests(t *testing.T, prefix string, update bool) {
 					log.Debug().Str("file", pi.Fset.Position(fn.Pos()).String()).
 						Str("function", fn.Name.Name).Msg("test parsing filter")
 
-					var flt string
+					var (
+						flt    FilterDef
+						err    error
+						errMsg string
+					)
 
-					var errMsg string
-
-					func() {
-						defer catchFatalError(&errMsg)
+					if update {
+						flt, err = testGen.parseUpdateFunction(fn.Name.Name, fn, pi)
+					} else {
+						flt, err = testGen.parseFilterFunction(fn.Name.Name, fn, pi)
+					}
 
-						if update {
-							_, flt = parseUpdateFunction(fn.Name.Name, fn, pi)
-						} else {
-							_, flt = parseFilterFunction(fn.Name.Name, fn, pi)
-						}
-					}()
+					if err != nil {
+						errMsg = diagnosticMessage(t, err)
+					}
 
 					validateTestComment(t, fn, update)
 
@@ -478,7 +1346,7 @@ func execTests(t *testing.T, prefix string, update bool) {
 						if errMsg != "" {
 							assert.NoError(t, fmt.Errorf("unexpected error: %v", errMsg))
 						} else {
-							assert.Equal(t, comment, strings.ReplaceAll(flt, "  ", " "))
+							assert.Equal(t, comment, strings.ReplaceAll(flt.Body, "  ", " "))
 						}
 					}
 				})
@@ -505,6 +1373,9 @@ var (
 	_ = parseFlowTest_8
 	_ = parseFlowTest_const_cond
 	_ = parseFlowTest_const_cond_1
+	_ = parseFlowTest_switch
+	_ = parseFlowTest_switch_tag
+	_ = parseFlowTest_switch_fallthrough
 )
 
 // Filter:
@@ -967,6 +1838,86 @@ func parseFlowTest_const_cond_1(d *Doc, _ Args) bool {
 	return false
 }
 
+// Filter:
+//
+//	{"$or":[
+//		{"field_int":1},
+//		{"$and":[
+//			{"field_int":{"$ne":1}},
+//			{"field_float":{{toJSON .Arg.ArgFloat}}}
+//		]}
+//	]}
+func parseFlowTest_switch(d *Doc, args Args) bool {
+	switch {
+	case d.FieldInt == 1:
+		return true
+	case d.FieldFloat == args.ArgFloat:
+		return true
+	}
+
+	return false
+}
+
+// Filter:
+//
+//	{"$or":[
+//		{"$and":[
+//			{"$or":[{"field_int":1},{"field_int":2}]},
+//			{"field_bool":true}
+//		]},
+//		{"$and":[
+//			{"field_int":{"$ne":1}},
+//			{"field_int":{"$ne":2}},
+//			{"field_int":3},
+//			{"field_float":{"$gt":10}}
+//		]}
+//	]}
+func parseFlowTest_switch_tag(d *Doc, _ Args) bool {
+	switch d.FieldInt {
+	case 1, 2:
+		return d.FieldBool
+	case 3:
+	default:
+		return false
+	}
+
+	return d.FieldFloat > 10
+}
+
+// Filter:
+//
+//	{"$or":[
+//		{"$and":[
+//			{"field_int":1},
+//			{"$or":[
+//				{"field_bool":true},
+//				{"$and":[
+//					{"field_bool":{"$ne":true}},
+//					{"field_float":{"$gt":10}}
+//				]}
+//			]}
+//		]},
+//		{"$and":[
+//			{"field_int":{"$ne":1}},
+//			{"field_int":{"$ne":3}},
+//			{"field_float":{"$gt":10}}
+//		]}
+//	]}
+func parseFlowTest_switch_fallthrough(d *Doc, _ Args) bool {
+	switch d.FieldInt {
+	case 1:
+		if d.FieldBool {
+			return true
+		}
+
+		fallthrough
+	default:
+		return d.FieldFloat > 10
+	case 3:
+		return false
+	}
+}
+
 func TestFiltersControlFlow(t *testing.T) {
 	execTests(t, "parseFlowTest_", false)
 }
@@ -979,10 +1930,16 @@ var (
 	_ = parseTestNegative_require_struct
 	_ = parseTestNegative_require_bool_return
 	_ = parseTestNegative_multi_name
-	_ = parseTestNegative_switch
+	_ = parseTestNegative_switch_break
+	_ = parseTestNegative_local_reassigned
+	_ = parseTestNegative_local_not_initialized
 	_ = parseUpdateFunc_1
 	_ = parseUpdateFunc_client_side
 	_ = parseUpdateFunc_simple_arg
+	_ = parseUpdateFunc_if_init
+	_ = parseUpdateFunc_arith_args
+	_ = parseUpdateFunc_arg_call
+	_ = parseUpdateFunc_optional_arg
 )
 
 // Error:
@@ -1059,25 +2016,41 @@ func parseTestNegative_multi_name(d, d1 *Doc) bool {
 
 // Error:
 //
-//	unsupported block statement:
-//
-//	 switch {
-//		 case d.FieldInt == 1:
-//			 return true
-//		 case d.FieldFloat == args.ArgFloat:
-//			 return true
-//	 }
-func parseTestNegative_switch(d *Doc, args Args) bool {
+//	break in switch is not supported: break
+func parseTestNegative_switch_break(d *Doc, args Args) bool {
 	switch {
 	case d.FieldInt == 1:
-		return true
-	case d.FieldFloat == args.ArgFloat:
+		if args.ArgBool {
+			break
+		}
+
 		return true
 	}
 
 	return false
 }
 
+// Error:
+//
+//	local variable 'limit' is reassigned, only variables assigned once are supported: limit = 10
+func parseTestNegative_local_reassigned(d *Doc, args Args) bool {
+	limit := args.ArgInt
+	if args.ArgBool {
+		limit = 10
+	}
+
+	return d.FieldInt > limit
+}
+
+// Error:
+//
+//	local variable should be initialized by single value: limit int
+func parseTestNegative_local_not_initialized(d *Doc, _ Args) bool {
+	var limit int
+
+	return d.FieldInt > limit
+}
+
 func TestFiltersNegative(t *testing.T) {
 	execTests(t, "parseTestNegative_", false)
 }
@@ -1085,7 +2058,11 @@ func TestFiltersNegative(t *testing.T) {
 func TestMain(m *testing.M) {
 	util.Configure(util.LogConfig{Level: "info", Format: "console"})
 
-	loadProgram(Program, []string{"."})
+	testGen = newGenerator(context.Background(), &Config{Log: &log.Logger})
+
+	if _, err := testGen.loadProgram([]string{"."}); err != nil {
+		log.Fatal().Err(err).Msg("failed to load test program")
+	}
 
 	os.Exit(m.Run())
 }
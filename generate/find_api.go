@@ -17,9 +17,7 @@ package generate
 import (
 	"go/ast"
 	"go/types"
-	"strings"
 
-	"github.com/rs/zerolog/log"
 	"github.com/tigrisdata/tigrisgen/util"
 	"golang.org/x/tools/go/packages"
 )
@@ -70,7 +68,7 @@ func signatureToFuncDecl(name string, sig *types.Signature, pi *packages.Package
 
 				fnSig, ok := obj.Type().(*types.Signature)
 				if !ok {
-					FatalWithExpr(pi, v, "expected function signature")
+					errorf(pi, v, "expected function signature")
 				}
 
 				if obj != nil && identical(name, sig, fn.Name.Name, fnSig) &&
@@ -80,18 +78,13 @@ func signatureToFuncDecl(name string, sig *types.Signature, pi *packages.Package
 					if fnSig.Recv() != nil {
 						tn, ok := fnSig.Recv().Type().(*types.Named)
 						if !ok {
-							FatalWithExpr(pi, v, "expected named type")
+							errorf(pi, v, "expected named type")
 						}
 
-						if fnSig.Recv().Pkg().Path() == "." {
-							name = fnSig.Recv().Pkg().Name() + "." + tn.Obj().Name() + "." + name
-						} else {
-							name = fnSig.Recv().Pkg().Path() + "." + tn.Obj().Name() + "." + name
-						}
-					} else if pi.PkgPath == "." {
-						name = pi.Name + "." + name
+						pkg := fnSig.Recv().Pkg()
+						name = runtimePkgPath(pkg.Path(), pkg.Name()) + "." + tn.Obj().Name() + "." + name
 					} else {
-						name = pi.PkgPath + "." + name
+						name = pkgFuncName(pi, name)
 					}
 
 					return name, fn
@@ -105,20 +98,40 @@ func signatureToFuncDecl(name string, sig *types.Signature, pi *packages.Package
 	return "", nil
 }
 
-func exprToFuncDecl(tp string, f ast.Expr, pi *packages.Package) (string, *ast.FuncDecl, *packages.Package) {
+// pkgFuncName returns the name of the package level function, as
+// reported by the runtime.
+func pkgFuncName(pi *packages.Package, name string) string {
+	return runtimePkgPath(pi.PkgPath, pi.Name) + "." + name
+}
+
+// runtimePkgPath returns the package path used by the runtime in the function
+// names. The functions of the main package are reported as main.Name.
+func runtimePkgPath(path string, name string) string {
+	if path == "." || name == "main" {
+		return name
+	}
+
+	return path
+}
+
+// loadPackage returns package by import path, loading it if necessary.
+func (g *generator) loadPackage(path string, pi *packages.Package, e ast.Node) *packages.Package {
+	if g.program[path] == nil {
+		if _, err := g.loadProgram([]string{path}); err != nil {
+			errorf(pi, e, "failed to load package %v: %v", path, err)
+		}
+	}
+
+	return g.program[path]
+}
+
+func (g *generator) exprToFuncDecl(tp string, f ast.Expr, pi *packages.Package) (string, *ast.FuncDecl, *packages.Package) {
 	switch a := f.(type) {
 	case *ast.Ident: // function
 		if a.Obj != nil && a.Obj.Kind == ast.Fun {
-			log.Debug().Str("API", tp).Str("name", a.Name).Int("pos", int(a.Pos())).Msg("detected simple function")
-
-			p := strings.TrimPrefix(Pwd, pi.Module.Dir)
-			if p != "" {
-				p = pi.Module.Path + p + "."
-			} else {
-				p = pi.Name + "."
-			}
+			g.log.Debug().Str("API", tp).Str("name", a.Name).Int("pos", int(a.Pos())).Msg("detected simple function")
 
-			return p + a.Name, a.Obj.Decl.(*ast.FuncDecl), pi
+			return pkgFuncName(pi, a.Name), a.Obj.Decl.(*ast.FuncDecl), pi
 		}
 	case *ast.SelectorExpr:
 		if s, ok := a.X.(*ast.Ident); ok { // method or external package function
@@ -129,16 +142,14 @@ func exprToFuncDecl(tp string, f ast.Expr, pi *packages.Package) (string, *ast.F
 				}
 
 				path := pkg.Imported().Path()
-				log.Debug().Str("API", tp).Str("type", pi.TypesInfo.TypeOf(f).String()).Str("package", path).
+				g.log.Debug().Str("API", tp).Str("type", pi.TypesInfo.TypeOf(f).String()).Str("package", path).
 					Msg("detected external package function")
 
-				if Program[path] == nil {
-					loadProgram(Program, []string{path})
-				}
+				ppi := g.loadPackage(path, pi, f)
 
-				nm, decl := signatureToFuncDecl(a.Sel.Name, sig, Program[path])
+				nm, decl := signatureToFuncDecl(a.Sel.Name, sig, ppi)
 
-				return nm, decl, Program[path]
+				return nm, decl, ppi
 			}
 
 			fn, ok := pi.TypesInfo.ObjectOf(a.Sel).(*types.Func)
@@ -146,7 +157,7 @@ func exprToFuncDecl(tp string, f ast.Expr, pi *packages.Package) (string, *ast.F
 				util.Fatal("not a function parameter: %v", pi.TypesInfo.Types[f].Type.String())
 			}
 
-			log.Debug().Str("API", tp).Str("name", a.Sel.Name).Int("pos", int(a.Pos())).Msg("detected document method")
+			g.log.Debug().Str("API", tp).Str("name", a.Sel.Name).Int("pos", int(a.Pos())).Msg("detected document method")
 
 			nm, decl := signatureToFuncDecl(a.Sel.Name, fn.Type().(*types.Signature), pi)
 
@@ -163,26 +174,27 @@ func exprToFuncDecl(tp string, f ast.Expr, pi *packages.Package) (string, *ast.F
 				}
 
 				path := pkg.Imported().Path()
-				log.Debug().Str("API", tp).Str("name", sig.String()).Str("package", path).
+				g.log.Debug().Str("API", tp).Str("name", sig.String()).Str("package", path).
 					Msg("detected external package document method")
 
-				if Program[path] == nil {
-					loadProgram(Program, []string{path})
-				}
+				ppi := g.loadPackage(path, pi, f)
 
-				nm, decl := signatureToFuncDecl(a.Sel.Name, sig, Program[path])
+				nm, decl := signatureToFuncDecl(a.Sel.Name, sig, ppi)
 
-				return nm, decl, Program[path]
+				return nm, decl, ppi
 			}
 
 			util.Fatal("unsupported API function parameter '%v.%v.%v'", sse.X, sse.Sel, a.Sel)
 		}
 	case *ast.FuncLit:
-		//		FatalWithExpr(a, "Closures are not supported yet")
-		return "anonymous", &ast.FuncDecl{Type: a.Type, Body: a.Body}, pi
+		name := closureName(pi, a)
+
+		g.log.Debug().Str("API", tp).Str("name", name).Msg("detected closure")
+
+		return name, &ast.FuncDecl{Type: a.Type, Body: a.Body}, pi
 	}
 
-	FatalWithExpr(pi, f, "unsupported API function parameter")
+	errorf(pi, f, "unsupported API function parameter")
 
 	return "", nil, nil
 }
@@ -0,0 +1,155 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tigrisdata/tigrisgen/expr"
+)
+
+// pureFuncs are the functions, which result depends only on the arguments,
+// so as they are evaluated by the generator, when called with constant arguments.
+var pureFuncs = map[string]any{
+	"time.Date":                        time.Date,
+	"github.com/google/uuid.MustParse": uuid.MustParse,
+}
+
+// pureValues are the package variables, which are never modified.
+var pureValues = map[string]any{
+	"time.UTC":                   time.UTC,
+	"github.com/google/uuid.Nil": uuid.Nil,
+}
+
+func objectKey(obj types.Object) string {
+	if obj == nil || obj.Pkg() == nil {
+		return ""
+	}
+
+	return obj.Pkg().Path() + "." + obj.Name()
+}
+
+// foldOperand evaluates the call of the pure function with constant
+// arguments. The result is marshaled by the json.Marshaler of its type,
+// the same way as the client marshals the arguments.
+func (f *funcParser) foldOperand(e ast.Expr) (expr.Operand, bool) {
+	v, ok := f.foldValue(e)
+	if !ok {
+		return expr.Operand{}, false
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		errorf(f.pi, e, "can't marshal the value: %v", err)
+	}
+
+	return expr.NewConstant(json.RawMessage(b)), true
+}
+
+func (f *funcParser) foldValue(e ast.Expr) (any, bool) {
+	if tv := f.pi.TypesInfo.Types[e]; tv.Value != nil {
+		return parseConst(tv.Value).Value, true
+	}
+
+	switch ee := unparen(e).(type) {
+	case *ast.Ident:
+		v, ok := pureValues[objectKey(f.pi.TypesInfo.Uses[ee])]
+		return v, ok
+	case *ast.SelectorExpr:
+		v, ok := pureValues[objectKey(f.pi.TypesInfo.Uses[ee.Sel])]
+		return v, ok
+	case *ast.CallExpr:
+		if isConversion(f.pi, ee) {
+			return f.foldConversion(ee)
+		}
+
+		var id *ast.Ident
+
+		switch fn := unparen(ee.Fun).(type) {
+		case *ast.Ident:
+			id = fn
+		case *ast.SelectorExpr:
+			id = fn.Sel
+		default:
+			return nil, false
+		}
+
+		fn, ok := pureFuncs[objectKey(f.pi.TypesInfo.Uses[id])]
+		if !ok {
+			return nil, false
+		}
+
+		return f.foldCall(ee, reflect.ValueOf(fn))
+	}
+
+	return nil, false
+}
+
+func (f *funcParser) foldCall(e *ast.CallExpr, fn reflect.Value) (any, bool) {
+	tp := fn.Type()
+
+	if tp.NumIn() != len(e.Args) || tp.IsVariadic() {
+		errorf(f.pi, e, "unexpected number of arguments")
+	}
+
+	args := make([]reflect.Value, 0, len(e.Args))
+
+	for i, a := range e.Args {
+		v, ok := f.foldValue(a)
+		if !ok {
+			errorf(f.pi, a, "constant argument expected")
+		}
+
+		rv := reflect.ValueOf(v)
+		if !rv.Type().ConvertibleTo(tp.In(i)) {
+			errorf(f.pi, a, "argument of type %v expected", tp.In(i))
+		}
+
+		args = append(args, rv.Convert(tp.In(i)))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			errorf(f.pi, e, "can't evaluate the call: %v", r)
+		}
+	}()
+
+	return fn.Call(args)[0].Interface(), true
+}
+
+// foldConversion evaluates conversion of the constant string to the byte slice.
+func (f *funcParser) foldConversion(e *ast.CallExpr) (any, bool) {
+	s, ok := f.pi.TypesInfo.TypeOf(e).Underlying().(*types.Slice)
+	if !ok {
+		return nil, false
+	}
+
+	if b, ok := s.Elem().Underlying().(*types.Basic); !ok || b.Kind() != types.Byte {
+		return nil, false
+	}
+
+	tv := f.pi.TypesInfo.Types[e.Args[0]]
+	if tv.Value == nil || tv.Value.Kind() != constant.String {
+		return nil, false
+	}
+
+	return []byte(constant.StringVal(tv.Value)), true
+}
@@ -16,20 +16,33 @@ package generate
 
 import (
 	_ "embed"
+	"go/parser"
+	"go/token"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 	"text/template"
-
-	"github.com/rs/zerolog/log"
 )
 
 //go:embed tigris.gen.gotmpl
 var genTempl string
 
+// funcsSource is the source of the template functions,
+// which are copied into the generated files.
+//
+//go:embed tmplfuncs.go
+var funcsSource string
+
+const funcsMarker = "// tigris:funcs\n"
+
 type FilterDef struct {
 	Name string
 	Body string
+	// Captured are the names of the variables captured by the closure,
+	// the client supplies their values in the Captured map of the
+	// template data.
+	Captured []string
 }
 
 type vars struct {
@@ -37,6 +50,34 @@ type vars struct {
 	Filters []FilterDef
 	Updates []FilterDef
 	Cmdline string
+	Imports []string
+	Funcs   string
+	// Captured maps the closures to the names of the captured variables
+	Captured map[string][]string
+}
+
+// templateFuncs returns the imports and the declarations
+// of the template functions.
+func templateFuncs() ([]string, string, error) {
+	i := strings.Index(funcsSource, funcsMarker)
+
+	f, err := parser.ParseFile(token.NewFileSet(), "tmplfuncs.go", funcsSource[:i], parser.ImportsOnly)
+	if err != nil {
+		return nil, "", err
+	}
+
+	imports := make([]string, 0, len(f.Imports))
+
+	for _, v := range f.Imports {
+		path, err := strconv.Unquote(v.Path.Value)
+		if err != nil {
+			return nil, "", err
+		}
+
+		imports = append(imports, path)
+	}
+
+	return imports, strings.TrimLeft(funcsSource[i+len(funcsMarker):], "\n"), nil
 }
 
 func writeGenFileLow(w io.Writer, pkg string, filters []FilterDef, updates []FilterDef) error {
@@ -45,26 +86,43 @@ func writeGenFileLow(w io.Writer, pkg string, filters []FilterDef, updates []Fil
 		return err
 	}
 
+	imports, funcs, err := templateFuncs()
+	if err != nil {
+		return err
+	}
+
 	v := vars{
 		Package: pkg,
 		Filters: filters,
 		Updates: updates,
 		Cmdline: "tigrisgen",
+		Imports: imports,
+		Funcs:   funcs,
+	}
+
+	for _, f := range append(append([]FilterDef{}, filters...), updates...) {
+		if len(f.Captured) == 0 {
+			continue
+		}
+
+		if v.Captured == nil {
+			v.Captured = make(map[string][]string)
+		}
+
+		v.Captured[f.Name] = f.Captured
 	}
 
 	return t.Execute(w, v)
 }
 
-func writeGenFile(name string, pkg string, filters []FilterDef, updates []FilterDef) error {
-	log.Info().Str("file_name", name).Str("package", pkg).Msg("generating")
+func (g *generator) writeGenFile(name string, pkg string, filters []FilterDef, updates []FilterDef) error {
+	g.log.Info().Str("file_name", name).Str("package", pkg).Msg("generating")
 
 	f, err := os.Create(name)
 	if err != nil {
 		return err
 	}
 
-	pkg = strings.TrimSuffix(pkg, "_test")
-
 	if err = writeGenFileLow(f, pkg, filters, updates); err != nil {
 		_ = f.Close()
 		return err
@@ -16,8 +16,16 @@ package generate
 
 import (
 	"bytes"
+	"context"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -39,13 +47,23 @@ func TestGenerate(t *testing.T) {
 	err := writeGenFileLow(&buf, "pkg_todo", flts, upds)
 	require.NoError(t, err)
 
+	_, funcs, err := templateFuncs()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(funcs, "// tigrisFuncs are"))
+
 	exp := `// Code generated by tigrisgen; DO NOT EDIT.
 
 package pkg_todo
 
 import (
+    "encoding/json"
+    "fmt"
+    "reflect"
+    "regexp"
+    "strconv"
+    "strings"
     "text/template"
-	"encoding/json"
+    "time"
 
     "github.com/tigrisdata/tigris-client-go/tigris"
 )
@@ -63,16 +81,7 @@ var tigrisUpdates = map[string]tigris.NativeFilter{
 }
 
 func parseTemplate(k string, v tigris.NativeFilter) tigris.NativeFilter {
-    c, err := template.New(k).Funcs(
-        template.FuncMap{
-            "toJSON": func(v any) (string, error) {
-                b, err := json.Marshal(v)
-                if err != nil {
-                    return "", err
-                }
-                return string(b), nil
-            },
-        }).Parse(v.Raw)
+    c, err := template.New(k).Funcs(tigrisFuncs).Parse(v.Raw)
     if err != nil {
         panic(err)
     }
@@ -82,6 +91,7 @@ func parseTemplate(k string, v tigris.NativeFilter) tigris.NativeFilter {
 	return v
 }
 
+` + funcs + `
 func init() {
     if tigris.Filters == nil {
         tigris.Filters = make(map[string]tigris.NativeFilter)
@@ -102,4 +112,73 @@ func init() {
 `
 
 	require.Equal(t, exp, buf.String())
+
+	_, err = parser.ParseFile(token.NewFileSet(), GenFileName, buf.Bytes(), 0)
+	require.NoError(t, err)
+}
+
+func TestGenerateCaptured(t *testing.T) {
+	flts := []FilterDef{
+		{Name: "main.FilterOne", Body: `{"Field3":{{toJSON .Arg}}}`},
+		{Name: "main.main.func1", Body: `{"Field3":{{toJSON (captured $ "a")}}}`, Captured: []string{"a", "b"}},
+	}
+
+	upds := []FilterDef{
+		{Name: "main.main.func2", Body: `{"$set":{"Field3":{{toJSON (captured $ "b")}}}}`, Captured: []string{"b"}},
+	}
+
+	var buf bytes.Buffer
+
+	err := writeGenFileLow(&buf, "main", flts, upds)
+	require.NoError(t, err)
+
+	assert.Contains(t, buf.String(), `var tigrisCaptured = map[string][]string{
+    "main.main.func1" : {"a", "b"},
+    "main.main.func2" : {"b"},
+}`)
+	assert.Contains(t, buf.String(), `    for k, v := range tigrisCaptured {
+        tigris.Captured[k] = v
+    }
+}`)
+
+	_, err = parser.ParseFile(token.NewFileSet(), GenFileName, buf.Bytes(), 0)
+	require.NoError(t, err)
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+
+	res, err := Run(context.Background(), Config{
+		Patterns:    []string{"."},
+		PackageName: "generate",
+		OutputDir:   dir,
+		APIPackage:  testAPIPackage,
+		Log:         &log.Logger,
+	})
+	require.NoError(t, err)
+	require.Empty(t, res.Diagnostics())
+
+	var (
+		flts  int
+		files int
+	)
+
+	for _, p := range res.Packages {
+		assert.Equal(t, "generate", p.Name)
+
+		flts += len(p.Filters)
+
+		if p.FileName != "" {
+			assert.Equal(t, filepath.Join(dir, GenFileName), p.FileName)
+			files++
+		}
+	}
+
+	assert.Equal(t, 4, flts)
+	assert.Equal(t, 1, files)
+
+	b, err := os.ReadFile(filepath.Join(dir, GenFileName))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "package generate\n")
+	assert.Contains(t, string(b), `"github.com/tigrisdata/tigrisgen/generate.FilterOne"`)
 }
@@ -0,0 +1,260 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"github.com/tigrisdata/tigrisgen/expr"
+	"golang.org/x/tools/go/packages"
+)
+
+// param returns the operand bound to the parameter of the inlined function.
+func (f *funcParser) param(id *ast.Ident) (expr.Operand, bool) {
+	v, ok := f.pi.TypesInfo.Uses[id].(*types.Var)
+	if !ok {
+		return expr.Operand{}, false
+	}
+
+	x, ok := f.params[v]
+
+	return x, ok
+}
+
+// paramSelector returns the operand of the selector on the struct argument
+// or the document field passed as the parameter of the inlined function.
+func (f *funcParser) paramSelector(id *ast.Ident, path []string) (expr.Operand, bool) {
+	x, ok := f.param(id)
+	if !ok || x.Pipeline != "" {
+		return expr.Operand{}, false
+	}
+
+	switch x.Type {
+	case expr.Arg:
+		if s, _ := x.Value.(string); s != "" {
+			path = append([]string{s}, path...)
+		}
+
+		x.Value = strings.Join(path, ".")
+	case expr.Field:
+		tp := f.pi.TypesInfo.Uses[id].Type()
+		if p, ok := tp.Underlying().(*types.Pointer); ok {
+			tp = p.Elem()
+		}
+
+		st, ok := tp.Underlying().(*types.Struct)
+		if !ok {
+			return expr.Operand{}, false
+		}
+
+		x.Value = fmt.Sprintf("%v.%v", x.Value, toFieldName(st, path))
+	default:
+		return expr.Operand{}, false
+	}
+
+	return x, true
+}
+
+// calledFunc returns the function or method called by the expression
+// and the receiver expression of the method call.
+func (f *funcParser) calledFunc(e *ast.CallExpr) (*types.Func, ast.Expr) {
+	switch fn := unparen(e.Fun).(type) {
+	case *ast.Ident:
+		obj, _ := f.pi.TypesInfo.Uses[fn].(*types.Func)
+		return obj, nil
+	case *ast.SelectorExpr:
+		obj, _ := f.pi.TypesInfo.Uses[fn.Sel].(*types.Func)
+
+		if sel, ok := f.pi.TypesInfo.Selections[fn]; ok && sel.Kind() == types.MethodVal {
+			return obj, fn.X
+		}
+
+		return obj, nil
+	}
+
+	return nil, nil
+}
+
+// funcDeclOf finds the declaration of the function in the package
+// of the function, loading the package if necessary.
+func (f *funcParser) funcDeclOf(fn *types.Func, e ast.Node) (*ast.FuncDecl, *packages.Package) {
+	pi := f.pi
+
+	if path := fn.Pkg().Path(); path != f.pi.PkgPath {
+		if pi = f.pi.Imports[path]; pi == nil || pi.TypesInfo == nil {
+			pi = f.loadPackage(path, f.pi, e)
+		}
+	}
+
+	for _, file := range pi.Syntax {
+		for _, d := range file.Decls {
+			if fd, ok := d.(*ast.FuncDecl); ok && fd.Body != nil {
+				if obj, ok := pi.TypesInfo.Defs[fd.Name].(*types.Func); ok && obj.FullName() == fn.FullName() {
+					return fd, pi
+				}
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// inlinable returns true if the function belongs to the user code: the
+// package being translated, the other packages of its module or the
+// packages loaded into the program. Functions of the standard library and
+// third-party modules are not inlined, as their bodies are not written to
+// be translated.
+func (f *funcParser) inlinable(fn *types.Func) bool {
+	path := fn.Pkg().Path()
+
+	if path == f.pi.PkgPath || f.program[path] != nil {
+		return true
+	}
+
+	m := f.pi.Module
+
+	return m != nil && (path == m.Path || strings.HasPrefix(path, m.Path+"/"))
+}
+
+// parseInlineCall inlines the call of the helper predicate or the document
+// method, which body is translatable to the filter:
+//
+//	func isActive(d *Doc) bool { return d.Status == "active" && !d.Deleted }
+//
+// The document is passed to the helper as is, other parameters
+// are substituted by the translated arguments of the call.
+func (f *funcParser) parseInlineCall(e *ast.CallExpr) (expr.Expr, bool) {
+	fn, recv := f.calledFunc(e)
+	if fn == nil || fn.Pkg() == nil {
+		return expr.Expr{}, false
+	}
+
+	sig := fn.Type().(*types.Signature)
+	if sig.Variadic() || sig.Results().Len() != 1 || !types.Identical(sig.Results().At(0).Type(), types.Typ[types.Bool]) {
+		return expr.Expr{}, false
+	}
+
+	if !f.inlinable(fn) {
+		return expr.Expr{}, false
+	}
+
+	decl, pi := f.funcDeclOf(fn, e)
+	if decl == nil {
+		return expr.Expr{}, false
+	}
+
+	for _, v := range f.inlined {
+		if v == fn.FullName() {
+			errorf(f.pi, e, "recursive call of %v is not supported", fn.Name())
+		}
+	}
+
+	f.log.Debug().Str("name", fn.FullName()).Msg("inlining function")
+
+	cf := funcParser{
+		generator: f.generator, docType: f.docType, argsType: f.argsType, pi: pi, fn: decl,
+		captured: f.captured,
+	}
+	cf.inlined = append(append(cf.inlined, f.inlined...), fn.FullName())
+
+	args := e.Args
+
+	var params []*ast.Field
+
+	if recv != nil {
+		params = append(params, decl.Recv.List...)
+		args = append([]ast.Expr{recv}, args...)
+	}
+
+	params = append(params, decl.Type.Params.List...)
+
+	i := 0
+
+	for _, p := range params {
+		if len(p.Names) == 0 {
+			i++
+			continue
+		}
+
+		for _, name := range p.Names {
+			f.bindParam(&cf, name, args[i])
+			i++
+		}
+	}
+
+	c, _ := cf.parseBlockStmt(decl.Body)
+
+	return c, true
+}
+
+// bindParam binds the parameter of the inlined function to the argument of the call.
+func (f *funcParser) bindParam(cf *funcParser, name *ast.Ident, arg ast.Expr) {
+	v, ok := cf.pi.TypesInfo.Defs[name].(*types.Var)
+	if !ok || name.Name == "_" {
+		return
+	}
+
+	root := arg
+
+	for {
+		if s, ok := root.(*ast.StarExpr); ok {
+			root = s.X
+		} else if p, ok := root.(*ast.ParenExpr); ok {
+			root = p.X
+		} else if u, ok := root.(*ast.UnaryExpr); ok && u.Op == token.AND {
+			root = u.X
+		} else {
+			break
+		}
+	}
+
+	if id, ok := root.(*ast.Ident); ok && !f.isLocal(id) {
+		if _, ok := f.param(id); !ok {
+			switch id.Name {
+			case f.doc:
+				cf.doc = name.Name
+				return
+			case f.args:
+				cf.args = name.Name
+				return
+			}
+		}
+	}
+
+	if b, ok := v.Type().Underlying().(*types.Basic); ok && b.Kind() == types.Bool {
+		if cf.conds == nil {
+			cf.conds = make(map[*types.Var]expr.Expr)
+		}
+
+		cf.conds[v] = f.parseBinaryExprLow(arg)
+
+		return
+	}
+
+	if cf.params == nil {
+		cf.params = make(map[*types.Var]expr.Operand)
+	}
+
+	// the address of the document field or argument is bound as the value
+	if u, ok := unparen(arg).(*ast.UnaryExpr); ok && u.Op == token.AND {
+		arg = u.X
+	}
+
+	cf.params[v] = f.parseOperand(arg)
+}
@@ -0,0 +1,184 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"github.com/tigrisdata/tigrisgen/expr"
+)
+
+// lenArg returns the argument of the len builtin call.
+func (f *funcParser) lenArg(e ast.Expr) (ast.Expr, bool) {
+	ce, ok := unparen(e).(*ast.CallExpr)
+	if !ok || len(ce.Args) != 1 {
+		return nil, false
+	}
+
+	id, ok := unparen(ce.Fun).(*ast.Ident)
+	if !ok {
+		return nil, false
+	}
+
+	b, ok := f.pi.TypesInfo.Uses[id].(*types.Builtin)
+
+	return ce.Args[0], ok && b.Name() == "len"
+}
+
+// parseLenOperand translates len of the argument to the template pipeline.
+func (f *funcParser) parseLenOperand(e ast.Expr) (expr.Operand, bool) {
+	a, ok := f.lenArg(e)
+	if !ok {
+		return expr.Operand{}, false
+	}
+
+	x := f.parseOperand(a)
+	if x.Type != expr.Arg {
+		errorf(f.pi, e, "len of the document field is only supported in comparisons")
+	}
+
+	return expr.NewPipeline("len " + x.ArgPath()), true
+}
+
+// parseLenCmp translates comparison of the length of the document field.
+// Comparisons are normalized to len > k form, which for arrays is
+// the existence of the element with index k, and for strings and maps
+// is only supported for k = 0, which is non-empty string or map check.
+func (f *funcParser) parseLenCmp(e *ast.BinaryExpr) (expr.Expr, bool) {
+	op := e.Op
+
+	a, ok := f.lenArg(e.X)
+	other := e.Y
+
+	if !ok {
+		if a, ok = f.lenArg(e.Y); !ok {
+			return expr.Expr{}, false
+		}
+
+		op, other = swapCmp(op), e.X
+	}
+
+	x := f.parseOperand(a)
+	if x.Type != expr.Field {
+		return expr.Expr{}, false
+	}
+
+	n := f.parseOperand(other)
+
+	var gt func(k int64) expr.Expr
+
+	switch f.pi.TypesInfo.TypeOf(a).Underlying().(type) {
+	case *types.Slice, *types.Array:
+		gt = func(k int64) expr.Expr {
+			if k < 0 {
+				return expr.True
+			}
+
+			return expr.NewExpr(expr.Exists, expr.NewField(fmt.Sprintf("%v.%v", x.Value, k)), expr.NewConstant(true))
+		}
+
+		if n.Type == expr.Arg {
+			return f.parseLenCmpArg(e, op, x, n), true
+		}
+	case *types.Basic:
+		gt = func(k int64) expr.Expr {
+			switch {
+			case k < 0:
+				return expr.True
+			case k == 0:
+				return expr.NewExpr(expr.Ne, x, expr.NewConstant(""))
+			}
+
+			errorf(f.pi, e, "only comparison of the string length with zero is supported")
+
+			return expr.Expr{}
+		}
+	case *types.Map:
+		if n.Type != expr.Constant {
+			errorf(f.pi, e, "only comparison of the map length with zero is supported")
+		}
+
+		// nil map is marshaled as null, empty map as {}
+		gt = func(k int64) expr.Expr {
+			switch {
+			case k < 0:
+				return expr.True
+			case k == 0:
+				return expr.And(
+					expr.NewExpr(expr.Ne, x, expr.NewConstant(nil)),
+					expr.NewExpr(expr.Ne, x, expr.NewConstant(map[string]any{})),
+				)
+			}
+
+			errorf(f.pi, e, "only comparison of the map length with zero is supported")
+
+			return expr.Expr{}
+		}
+	}
+
+	k, ok := n.Value.(int64)
+	if n.Type != expr.Constant || !ok {
+		errorf(f.pi, other, "integer constant or argument expected in the length comparison")
+	}
+
+	switch op {
+	case token.GTR:
+		return gt(k), true
+	case token.GEQ:
+		return gt(k - 1), true
+	case token.LSS:
+		return expr.Negate(gt(k - 1)), true
+	case token.LEQ:
+		return expr.Negate(gt(k)), true
+	case token.EQL:
+		return expr.And(gt(k-1), expr.Negate(gt(k))), true
+	case token.NEQ:
+		return expr.Negate(expr.And(gt(k-1), expr.Negate(gt(k)))), true
+	}
+
+	return expr.Expr{}, false
+}
+
+// parseLenCmpArg translates comparison of the array length with the argument,
+// the index of the element is substituted by the template.
+// The length is always greater than the negative argument, which is checked
+// on the client, as the negative index doesn't exist in the array.
+// The >= and < comparisons are translated as > and <= of the argument
+// decremented by the template.
+func (f *funcParser) parseLenCmpArg(e ast.Node, op token.Token, x expr.Operand, n expr.Operand) expr.Expr {
+	switch op {
+	case token.GEQ, token.LSS:
+		n = expr.NewPipeline("sub " + n.ArgPath() + " 1")
+	}
+
+	c := expr.Or(
+		expr.NewExpr(expr.Lt, n, expr.NewConstant(int64(0))).Client(),
+		expr.NewExpr(expr.Exists, expr.NewField(fmt.Sprintf("%v.{{%v}}", x.Value, n.ArgPath())), expr.NewConstant(true)),
+	)
+
+	switch op {
+	case token.GTR, token.GEQ:
+		return c
+	case token.LEQ, token.LSS:
+		return expr.Negate(c)
+	}
+
+	errorf(f.pi, e, "only ordered comparisons of the array length with the argument are supported")
+
+	return expr.Expr{}
+}
@@ -0,0 +1,277 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"github.com/tigrisdata/tigrisgen/expr"
+)
+
+// local returns the definition of the local variable referred by the identifier.
+// Local variables are substituted by their definitions during translation.
+func (f *funcParser) local(id *ast.Ident) (ast.Expr, bool) {
+	v, ok := f.pi.TypesInfo.Uses[id].(*types.Var)
+	if !ok {
+		return nil, false
+	}
+
+	def, ok := f.locals[v]
+
+	return def, ok
+}
+
+// localCond returns the condition bound to the ok variable of
+// the comma-ok map lookup or type assertion.
+func (f *funcParser) localCond(id *ast.Ident) (expr.Expr, bool) {
+	v, ok := f.pi.TypesInfo.Uses[id].(*types.Var)
+	if !ok {
+		return expr.Expr{}, false
+	}
+
+	c, ok := f.conds[v]
+
+	return c, ok
+}
+
+// isLocal returns true if the identifier refers to the local variable.
+func (f *funcParser) isLocal(id *ast.Ident) bool {
+	if _, ok := f.local(id); ok {
+		return true
+	}
+
+	_, ok := f.localCond(id)
+
+	return ok
+}
+
+// defineLocal registers the definition of the local variable.
+func (f *funcParser) defineLocal(name *ast.Ident, def ast.Expr) {
+	if name.Name == "_" {
+		return
+	}
+
+	v, ok := f.pi.TypesInfo.Defs[name].(*types.Var)
+	if !ok {
+		errorf(f.pi, name, "local variable redeclaration is not supported")
+	}
+
+	f.checkNotReassigned(v)
+
+	if f.locals == nil {
+		f.locals = make(map[*types.Var]ast.Expr)
+	}
+
+	f.log.Debug().Str("name", name.Name).Msg("local variable")
+
+	f.locals[v] = def
+}
+
+// defineCond registers the ok variable of the comma-ok form.
+func (f *funcParser) defineCond(name *ast.Ident, cond expr.Expr) {
+	if name.Name == "_" {
+		return
+	}
+
+	v, ok := f.pi.TypesInfo.Defs[name].(*types.Var)
+	if !ok {
+		errorf(f.pi, name, "local variable redeclaration is not supported")
+	}
+
+	f.checkNotReassigned(v)
+
+	if f.conds == nil {
+		f.conds = make(map[*types.Var]expr.Expr)
+	}
+
+	f.conds[v] = cond
+}
+
+// parseIfInit registers local variables defined in the if init section.
+// Variables are scoped to the if/else chain by the type checker.
+func (f *funcParser) parseIfInit(stmt ast.Stmt) {
+	switch e := stmt.(type) {
+	case *ast.AssignStmt:
+		if e.Tok != token.DEFINE {
+			break
+		}
+
+		f.parseAssignLocals(e)
+
+		return
+	case *ast.DeclStmt:
+		f.parseDeclLocals(e)
+		return
+	}
+
+	errorf(f.pi, stmt, "unsupported if init statement")
+}
+
+// tmplValue renders the operand as the template pipeline argument.
+func (f *funcParser) tmplValue(x expr.Operand, e ast.Expr) string {
+	switch x.Type {
+	case expr.Arg:
+		return x.ArgPath()
+	case expr.Constant:
+		b, err := json.Marshal(x.Value)
+		if err != nil {
+			errorf(f.pi, e, "%v", err)
+		}
+
+		return string(b)
+	}
+
+	errorf(f.pi, e, "argument or constant expected")
+
+	return ""
+}
+
+// parseCommaOk translates the ok result of map lookup or type assertion
+// on arguments to the client side condition,
+// map lookup on the document field is translated to the presence check.
+func (f *funcParser) parseCommaOk(e ast.Expr) expr.Expr {
+	var pipeline string
+
+	switch ee := unparen(e).(type) {
+	case *ast.IndexExpr:
+		if _, ok := f.pi.TypesInfo.TypeOf(ee.X).Underlying().(*types.Map); !ok {
+			errorf(f.pi, e, "map lookup expected")
+		}
+
+		m := f.parseOperand(ee.X)
+		if m.Type == expr.Field {
+			return expr.NewExpr(expr.Exists, f.parseOperand(ee), expr.NewConstant(true))
+		}
+
+		if m.Type != expr.Arg {
+			errorf(f.pi, e, "comma-ok map lookup is only supported on arguments and document fields")
+		}
+
+		pipeline = fmt.Sprintf("hasKey %v %v", m.ArgPath(), f.tmplValue(f.parseOperand(ee.Index), ee.Index))
+	case *ast.TypeAssertExpr:
+		x := f.parseOperand(ee.X)
+		if x.Type != expr.Arg {
+			errorf(f.pi, e, "comma-ok type assertion is only supported on arguments")
+		}
+
+		tp := f.pi.TypesInfo.TypeOf(ee.Type)
+		if types.IsInterface(tp) {
+			errorf(f.pi, ee.Type, "type assertion to interface is not supported")
+		}
+
+		name := types.TypeString(tp, func(p *types.Package) string { return p.Name() })
+
+		pipeline = fmt.Sprintf("isType %v %q", x.ArgPath(), name)
+	default:
+		errorf(f.pi, e, "map lookup or type assertion expected")
+	}
+
+	return expr.NewExpr(expr.Eq, expr.NewPipeline(pipeline), expr.NewConstant(true)).Client()
+}
+
+// parseAssignLocals registers local variables defined by v := expr.
+func (f *funcParser) parseAssignLocals(stmt *ast.AssignStmt) {
+	if len(stmt.Lhs) == 2 && len(stmt.Rhs) == 1 {
+		val, _ := stmt.Lhs[0].(*ast.Ident)
+		ok, _ := stmt.Lhs[1].(*ast.Ident)
+
+		if val == nil || ok == nil {
+			errorf(f.pi, stmt, "unsupported comma-ok assignment")
+		}
+
+		f.defineLocal(val, stmt.Rhs[0])
+		f.defineCond(ok, f.parseCommaOk(stmt.Rhs[0]))
+
+		return
+	}
+
+	if len(stmt.Lhs) != len(stmt.Rhs) {
+		errorf(f.pi, stmt, "multi-value assignment is not supported")
+	}
+
+	for i, l := range stmt.Lhs {
+		name, ok := l.(*ast.Ident)
+		if !ok {
+			errorf(f.pi, l, "unsupported local variable")
+		}
+
+		f.defineLocal(name, stmt.Rhs[i])
+	}
+}
+
+// parseDeclLocals registers local variables declared by var v = expr.
+func (f *funcParser) parseDeclLocals(stmt *ast.DeclStmt) {
+	gd, ok := stmt.Decl.(*ast.GenDecl)
+	if !ok {
+		errorf(f.pi, stmt, "unsupported declaration")
+	}
+
+	if gd.Tok == token.CONST || gd.Tok == token.TYPE {
+		return // constants are folded by the type checker
+	}
+
+	for _, s := range gd.Specs {
+		vs := s.(*ast.ValueSpec)
+
+		if len(vs.Values) != len(vs.Names) {
+			errorf(f.pi, vs, "local variable should be initialized by single value")
+		}
+
+		for i, name := range vs.Names {
+			f.defineLocal(name, vs.Values[i])
+		}
+	}
+}
+
+// checkNotReassigned reports an error if the variable is assigned
+// anywhere in the function after the definition.
+func (f *funcParser) checkNotReassigned(v *types.Var) {
+	isVar := func(e ast.Expr) bool {
+		id, ok := unparen(e).(*ast.Ident)
+		return ok && f.pi.TypesInfo.Uses[id] == v
+	}
+
+	ast.Inspect(f.fn.Body, func(n ast.Node) bool {
+		switch e := n.(type) {
+		case *ast.AssignStmt:
+			for _, l := range e.Lhs {
+				if isVar(l) {
+					errorf(f.pi, e, "local variable '%v' is reassigned, only variables assigned once are supported",
+						v.Name())
+				}
+			}
+		case *ast.IncDecStmt:
+			if isVar(e.X) {
+				errorf(f.pi, e, "local variable '%v' is reassigned, only variables assigned once are supported",
+					v.Name())
+			}
+		case *ast.RangeStmt:
+			if isVar(e.Key) || isVar(e.Value) {
+				errorf(f.pi, e, "local variable '%v' is assigned in the loop, only variables assigned once are supported",
+					v.Name())
+			}
+		case *ast.UnaryExpr:
+			if e.Op == token.AND && isVar(e.X) {
+				errorf(f.pi, e, "address of the local variable '%v' is not supported", v.Name())
+			}
+		}
+
+		return true
+	})
+}
@@ -15,12 +15,12 @@
 package generate
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"go/ast"
-	"go/printer"
 	"go/types"
 	"os"
-	"reflect"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -28,243 +28,308 @@ import (
 	"golang.org/x/tools/go/packages"
 )
 
-var tigrisPkg = "github.com/tigrisdata/tigris-client-go/tigris"
-
-// Program source loaded into memory.
-var (
-	Program = map[string]*packages.Package{}
-	Pwd     string
-)
-
-func getFilterFunc(name string, pi *packages.Package, ident ast.Expr, flt ast.Expr, upd ast.Expr, filtersFNs []ast.Expr,
-	updatesFNs []ast.Expr,
-) ([]ast.Expr, []ast.Expr) {
-	if ssse, ok := ident.(*ast.Ident); ok {
-		if pkg, ok := pi.TypesInfo.ObjectOf(ssse).(*types.PkgName); ok {
-			if pkg.Imported().Path() == tigrisPkg {
-				filtersFNs = append(filtersFNs, flt)
+// apiCall is the call of the API function found in the source.
+type apiCall struct {
+	api     string
+	filters []ast.Expr
+	updates []ast.Expr
+}
 
-				if upd != nil {
-					updatesFNs = append(updatesFNs, upd)
-				}
+// callArgs returns the call arguments at the given positions.
+func (g *generator) callArgs(api string, ce *ast.CallExpr, pos []int) []ast.Expr {
+	var res []ast.Expr
 
-				log.Debug().Str("API", name).Str("filter", pi.TypesInfo.Types[flt].Type.String()).
-					Str("update", pi.TypesInfo.Types[upd].Type.String()).Msg("get params")
-			}
+	for _, v := range pos {
+		if v >= len(ce.Args) {
+			g.log.Debug().Str("API", api).Int("position", v).Msg("argument not found in the call")
+			continue
 		}
+
+		res = append(res, ce.Args[v])
 	}
 
-	return filtersFNs, updatesFNs
+	return res
 }
 
-func findAPIcalls(node *ast.File, pi *packages.Package, apiName string) ([]ast.Expr, []ast.Expr) {
-	filtersFNs := make([]ast.Expr, 0)
-	updatesFNs := make([]ast.Expr, 0)
+// findAPIcalls finds calls of the API functions in the file.
+// The callee is resolved by the type identity of the called function,
+// so dot imports, function and method values are recognized as well.
+func (g *generator) findAPIcalls(node *ast.File, pi *packages.Package) []apiCall {
+	var calls []apiCall
 
 	ast.Inspect(node, func(n ast.Node) bool {
 		ce, ok := n.(*ast.CallExpr)
-		if ok {
-			var arg2, arg3 ast.Expr
-			if len(ce.Args) > 2 {
-				arg2 = ce.Args[2]
-			}
+		if !ok {
+			return true
+		}
 
-			if len(ce.Args) > 3 {
-				arg3 = ce.Args[3]
+		fns, reason := g.resolveCallee(pi, ce.Fun, make(map[*types.Var]bool))
+		if reason != "" {
+			g.log.Debug().Str("call", types.ExprString(ce.Fun)).Str("pos", pi.Fset.Position(ce.Pos()).String()).
+				Str("reason", reason).Msg("skipping call")
+
+			return true
+		}
+
+		for _, f := range fns {
+			path, name := funcKey(f)
+
+			fn := g.apis.lookup(path, name)
+			if fn == nil {
+				g.log.Trace().Str("call", types.ExprString(ce.Fun)).Str("func", path+"."+name).
+					Msg("skipping call, not an API function")
+
+				continue
 			}
 
-			// tigris.Update(...
-			if se, ok := ce.Fun.(*ast.SelectorExpr); ok {
-				if se.Sel.Name == apiName {
-					filtersFNs, updatesFNs = getFilterFunc(apiName, pi, se.X, arg2, arg3, filtersFNs, updatesFNs)
-				}
+			c := apiCall{
+				api:     path + "." + fn.Name,
+				filters: g.callArgs(fn.Name, ce, fn.Filters),
+				updates: g.callArgs(fn.Name, ce, fn.Updates),
 			}
-			// tigris.Update[Doc](...
-			if se, ok := ce.Fun.(*ast.IndexExpr); ok {
-				if sse, ok := se.X.(*ast.SelectorExpr); ok {
-					if sse.Sel.Name == apiName {
-						filtersFNs, updatesFNs = getFilterFunc(apiName, pi, sse.X, arg2, arg3, filtersFNs, updatesFNs)
-					}
-				}
+
+			for _, v := range c.filters {
+				g.log.Debug().Str("API", c.api).Str("filter", pi.TypesInfo.Types[v].Type.String()).Msg("get params")
 			}
-			// tigris.Update[Doc, Args](...
-			if se, ok := ce.Fun.(*ast.IndexListExpr); ok {
-				if sse, ok := se.X.(*ast.SelectorExpr); ok {
-					if sse.Sel.Name == apiName {
-						filtersFNs, updatesFNs = getFilterFunc(apiName, pi, sse.X, arg2, arg3, filtersFNs, updatesFNs)
-					}
-				}
+
+			for _, v := range c.updates {
+				g.log.Debug().Str("API", c.api).Str("update", pi.TypesInfo.Types[v].Type.String()).Msg("get params")
 			}
 
-			return true
+			calls = append(calls, c)
 		}
 
 		return true
 	})
 
-	return filtersFNs, updatesFNs
+	return calls
 }
 
-func loadProgram(program map[string]*packages.Package, args []string) {
+// loadProgram loads packages matching the patterns into the program.
+// It returns loaded root packages.
+func (g *generator) loadProgram(args []string) ([]*packages.Package, error) {
 	start := time.Now()
 
-	cfg := packages.Config{Tests: true, Mode: packages.NeedName | packages.NeedDeps | packages.NeedSyntax |
-		packages.NeedTypes | packages.NeedTypesInfo | packages.NeedModule}
+	cfg := g.loadCfg
+	cfg.Tests = true
+	cfg.Mode = packages.NeedName | packages.NeedDeps | packages.NeedSyntax |
+		packages.NeedTypes | packages.NeedTypesInfo | packages.NeedTypesSizes | packages.NeedModule
 
 	pkgs, err := packages.Load(&cfg, args...)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	if diags := packageErrors(pkgs); len(diags) > 0 {
+		return nil, diags
 	}
 
 	for _, v := range pkgs {
-		log.Info().Str("id", v.ID).Str("path", v.PkgPath).Str("name", v.Name).Msg("loading package")
-		program[v.ID] = v
+		g.log.Info().Str("id", v.ID).Str("path", v.PkgPath).Str("name", v.Name).Msg("loading package")
+		g.program[v.ID] = v
 	}
 
-	if packages.PrintErrors(pkgs) != 0 {
-		os.Exit(1)
+	g.findWrappers(pkgs)
+
+	g.log.Info().Dur("duration", time.Since(start)).Msg("parse time")
+
+	return pkgs, nil
+}
+
+// funcDecl is the declaration of the function passed as API parameter.
+type funcDecl struct {
+	name string
+	body *ast.FuncDecl
+	pi   *packages.Package
+}
+
+// lookupFuncDecls finds declarations of the functions passed as API parameter.
+// Parameter, which is a variable or struct field, can refer to several functions.
+func (g *generator) lookupFuncDecls(api string, ff ast.Expr, pi *packages.Package) (decls []funcDecl, err error) {
+	defer catch(pi, "", ff, &err)
+
+	for _, v := range g.funcValues(pi, ff, make(map[*types.Var]bool)) {
+		name, body, fpi := g.exprToFuncDecl(api, v, pi)
+		decls = append(decls, funcDecl{name: name, body: body, pi: fpi})
 	}
 
-	log.Info().Dur("duration", time.Since(start)).Msg("parse time")
+	return
 }
 
-func findAndParseAPI(api string, f *ast.File, pi *packages.Package,
-	filters []FilterDef, updates []FilterDef,
+func (g *generator) findAndParseAPI(call apiCall, pi *packages.Package,
+	filters []FilterDef, updates []FilterDef, diags Diagnostics,
 	fltName map[string]bool, updName map[string]bool,
-) ([]FilterDef, []FilterDef) {
-	log.Debug().Str("API", api).Msg("parsing")
-
-	flt, u := findAPIcalls(f, pi, api)
-
-	if api != "UpdateAll" {
-		for _, ff := range flt {
-			name, body, pi := exprToFuncDecl(api, ff, pi)
-			if body != nil {
-				if fltName[name] {
-					log.Debug().Str("name", name).Msg("skipping duplicate filter")
-					continue
-				}
-
-				fltName[name] = true
-				n, flt := parseFilterFunction(name, body, pi)
-				log.Info().Str("name", n).Str("filter", flt).Msg("filter")
-				filters = append(filters, FilterDef{n, flt})
-			} else {
-				log.Warn().Str("package", pi.Name).Str("expr",
-					reflect.TypeOf(ff).Name()).Msg("not a filter function")
+) ([]FilterDef, []FilterDef, Diagnostics) {
+	g.log.Debug().Str("API", call.api).Msg("parsing")
+
+	for _, ff := range call.filters {
+		if g.forwarded[ff] {
+			g.log.Debug().Str("API", call.api).Msg("skipping filter forwarded by wrapper")
+			continue
+		}
+
+		decls, err := g.lookupFuncDecls(call.api, ff, pi)
+		if err != nil {
+			diags.add(err)
+			continue
+		}
+
+		for _, d := range decls {
+			if d.body == nil {
+				diags.warnf(d.pi, ff, "not a filter function")
+				continue
 			}
+
+			if fltName[d.name] {
+				g.log.Debug().Str("name", d.name).Msg("skipping duplicate filter")
+				continue
+			}
+
+			fltName[d.name] = true
+
+			flt, err := g.parseFilterFunction(d.name, d.body, d.pi)
+			if err != nil {
+				diags.add(err)
+				continue
+			}
+
+			g.log.Info().Str("name", flt.Name).Str("filter", flt.Body).Msg("filter")
+			filters = append(filters, flt)
 		}
 	}
 
-	if api != "Update" && api != "UpdateOne" && api != "UpdateAll" {
-		return filters, updates
-	}
+	for _, ff := range call.updates {
+		if g.forwarded[ff] {
+			g.log.Debug().Str("API", call.api).Msg("skipping update forwarded by wrapper")
+			continue
+		}
 
-	if api == "UpdateAll" {
-		u = flt
-	}
+		decls, err := g.lookupFuncDecls(call.api, ff, pi)
+		if err != nil {
+			diags.add(err)
+			continue
+		}
+
+		for _, d := range decls {
+			if d.body == nil {
+				diags.warnf(d.pi, ff, "not an update function")
+				continue
+			}
+
+			if updName[d.name] {
+				g.log.Debug().Str("name", d.name).Msg("skipping duplicate update")
+				continue
+			}
+
+			updName[d.name] = true
 
-	for _, ff := range u {
-		name, body, pi := exprToFuncDecl(api, ff, pi)
-		if body != nil {
-			if updName[name] {
-				log.Debug().Str("name", name).Msg("skipping duplicate update")
+			upd, err := g.parseUpdateFunction(d.name, d.body, d.pi)
+			if err != nil {
+				diags.add(err)
 				continue
 			}
 
-			updName[name] = true
-			n, upd := parseUpdateFunction(name, body, pi)
-			log.Info().Str("name", n).Str("update", upd).Msg("update")
-			updates = append(updates, FilterDef{n, upd})
-		} else {
-			log.Warn().Str("package", pi.Name).Str("expr",
-				reflect.TypeOf(ff).Name()).Msg("not an update function")
+			g.log.Info().Str("name", upd.Name).Str("update", upd.Body).Msg("update")
+			updates = append(updates, upd)
 		}
 	}
 
-	return filters, updates
+	return filters, updates, diags
 }
 
-func findAndParse(pi *packages.Package) ([]FilterDef, []FilterDef) {
+// findAndParse finds API calls in the package and translates
+// filter and update functions passed to them.
+// Translation continues after an error, so as all the problems in
+// the package are reported in the returned diagnostics.
+func (g *generator) findAndParse(pi *packages.Package) ([]FilterDef, []FilterDef, Diagnostics) {
 	var (
 		filters []FilterDef
 		updates []FilterDef
+		diags   Diagnostics
 	)
 
 	// deduplicate functions
 	fltName := make(map[string]bool)
 	updName := make(map[string]bool)
 
-	log.Debug().Str("package", pi.Name).Msg("processing package")
-
-	apis := []string{"Update", "UpdateOne", "UpdateAll", "Read", "ReadOne", "ReadWithOptions", "Delete", "DeleteOne"}
+	g.log.Debug().Str("package", pi.Name).Msg("processing package")
 
 	for _, f := range pi.Syntax {
-		log.Debug().Str("file", pi.Fset.File(f.Pos()).Name()).Msg("processing file")
+		g.log.Debug().Str("file", pi.Fset.File(f.Pos()).Name()).Msg("processing file")
 
-		for _, v := range apis {
-			filters, updates = findAndParseAPI(v, f, pi, filters, updates, fltName, updName)
+		for _, v := range g.findAPIcalls(f, pi) {
+			filters, updates, diags = g.findAndParseAPI(v, pi, filters, updates, diags, fltName, updName)
 		}
 	}
 
-	return filters, updates
+	return filters, updates, diags
 }
 
-func MainLow() {
+// MainLow generates filters and updates for the package in the current directory.
+// This is the entry point of the go:generate command.
+// Diagnostics of all the problems found in the package are logged,
+// the error returned only summarizes them.
+func MainLow() error {
 	util.Configure(util.LogConfig{Format: "console", Level: "info"})
 
-	s, _ := os.Getwd()
-	log.Debug().Strs("args", os.Args).Str("pwd", s).Msg("Starting")
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Strs("args", os.Args).Str("pwd", pwd).Msg("Starting")
 
-	var err error
+	cfg := Config{
+		Patterns:    []string{pwd},
+		PackageName: os.Getenv("GOPACKAGE"),
+		OutputDir:   pwd,
+		Log:         &log.Logger,
+	}
 
-	Pwd, err = os.Getwd()
+	name, err := FindConfigFile(pwd)
 	if err != nil {
-		util.Fatal("%v", err)
+		return err
 	}
 
-	loadProgram(Program, []string{Pwd})
+	if name != "" {
+		log.Debug().Str("file_name", name).Msg("reading config")
 
-	for _, pi := range Program {
-		if pi.Name != os.Getenv("GOPACKAGE") {
-			continue
+		var fc *FileConfig
+
+		if fc, err = ReadConfigFile(name); err != nil {
+			return err
 		}
 
-		log.Debug().Str("package", pi.Name).Msg("processing")
+		cfg.APIs = fc.APIs
+	}
 
-		flts, upds := findAndParse(pi)
+	res, err := Run(context.Background(), cfg)
 
-		if len(flts) == 0 && len(upds) == 0 {
-			log.Debug().Msg("No filters or updates found in package")
-			continue
+	for _, v := range res.Diagnostics() {
+		ev := log.Error()
+		if v.Severity == SeverityWarning {
+			ev = log.Warn()
 		}
 
-		log.Debug().Interface("filters", flts).Interface("updates", upds).Msg("parsed")
-
-		err := writeGenFile("tigris.gen.go", pi.Name, flts, upds)
-		if err != nil {
-			panic(err)
-		}
+		ev.Str("pos", v.Pos.String()).Str("func", v.Func).Str("expr", v.Expr).Msg(v.Msg)
 	}
 
 	log.Debug().Msg("Finished")
-}
 
-func fatalWithExpr(pi *packages.Package, e ast.Node, format string, args ...any) {
-	if Program != nil {
-		pos := pi.Fset.Position(e.Pos())
-		log.Error().CallerSkipFrame(1).Str("line", pos.String()).Msgf(format, args...)
+	// the diagnostics are logged above, so only the summary is returned
+	var d Diagnostics
 
-		cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 4}
+	if errors.As(err, &d) {
+		n := 0
 
-		fmt.Fprintf(os.Stderr, ">>>>>>>>>>>>>>>>>>>>>>\n")
-		_ = cfg.Fprint(os.Stderr, pi.Fset, e)
-		fmt.Fprintf(os.Stderr, "\n>>>>>>>>>>>>>>>>>>>>>>\n")
-	} else {
-		log.Error().CallerSkipFrame(1).Msgf(format, args...)
+		for _, v := range d {
+			if v.Severity == SeverityError {
+				n++
+			}
+		}
+
+		return fmt.Errorf("%d error(s) found", n)
 	}
 
-	os.Exit(1)
+	return err
 }
-
-var FatalWithExpr = fatalWithExpr
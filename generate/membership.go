@@ -0,0 +1,174 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"github.com/tigrisdata/tigrisgen/expr"
+)
+
+// parseListOperand parses the slice argument or the slice literal of constants.
+func (f *funcParser) parseListOperand(e ast.Expr) expr.Operand {
+	if lit, ok := unparen(e).(*ast.CompositeLit); ok {
+		l := make([]any, 0, len(lit.Elts))
+
+		for _, v := range lit.Elts {
+			c := f.parseOperand(v)
+			if c.Type != expr.Constant {
+				errorf(f.pi, v, "only constants are supported in the slice literal")
+			}
+
+			l = append(l, c.Value)
+		}
+
+		return expr.NewConstant(l)
+	}
+
+	x := f.parseOperand(e)
+
+	switch f.pi.TypesInfo.TypeOf(e).Underlying().(type) {
+	case *types.Slice, *types.Array:
+		if x.Type == expr.Arg {
+			return x
+		}
+	}
+
+	errorf(f.pi, e, "argument or constant slice expected")
+
+	return expr.Operand{}
+}
+
+// parseMembership builds $in condition of the document field and the list.
+func (f *funcParser) parseMembership(e ast.Node, field ast.Expr, list expr.Operand) expr.Expr {
+	x := f.parseOperand(field)
+	if x.Type != expr.Field {
+		errorf(f.pi, e, "document field is expected as the searched value")
+	}
+
+	return expr.NewExpr(expr.In, x, list)
+}
+
+// parseRangeStmt translates the loop over the argument slice:
+//
+//	for _, v := range args.List {
+//		if d.Field == v {
+//			...
+//		}
+//	}
+//
+// to the if statement with d.Field in args.List condition,
+// and the loop over the document slice field:
+//
+//	for _, e := range d.Items {
+//		if e.Field1 > 5 && e.Field2 == args.S {
+//			...
+//		}
+//	}
+//
+// to the if statement with the element match condition.
+func (f *funcParser) parseRangeStmt(stmt *ast.RangeStmt) (expr.Expr, *expr.Expr) {
+	if k, ok := stmt.Key.(*ast.Ident); stmt.Key != nil && (!ok || k.Name != "_") {
+		errorf(f.pi, stmt.Key, "loop index is not supported")
+	}
+
+	val, _ := stmt.Value.(*ast.Ident)
+	if val == nil || stmt.Tok != token.DEFINE {
+		errorf(f.pi, stmt, "loop value variable is expected")
+	}
+
+	v, _ := f.pi.TypesInfo.Defs[val].(*types.Var)
+
+	if len(stmt.Body.List) != 1 {
+		errorf(f.pi, stmt.Body, "loop body should be single if statement")
+	}
+
+	ifs, ok := stmt.Body.List[0].(*ast.IfStmt)
+	if !ok || ifs.Init != nil || ifs.Else != nil {
+		errorf(f.pi, stmt.Body.List[0], "loop body should be single if statement without init and else")
+	}
+
+	ast.Inspect(ifs.Body, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && v != nil && f.pi.TypesInfo.Uses[id] == v {
+			errorf(f.pi, id, "loop variable is only supported in the loop condition")
+		}
+
+		return true
+	})
+
+	if _, ok := unparen(stmt.X).(*ast.CompositeLit); !ok {
+		if x := f.parseOperand(stmt.X); x.Type == expr.Field {
+			return f.parseIfBranches(ifs, f.parseElemMatch(stmt.X, x, val, ifs.Cond))
+		}
+	}
+
+	field := f.loopMatchField(ifs.Cond, v)
+
+	return f.parseIfBranches(ifs, f.parseMembership(ifs.Cond, field, f.parseListOperand(stmt.X)))
+}
+
+// parseElemMatch parses the condition on the element of the document
+// slice field. Field names in the condition are relative to the element,
+// so as the condition is matched against the same element.
+func (f *funcParser) parseElemMatch(list ast.Expr, x expr.Operand, elem *ast.Ident, cond ast.Expr) expr.Expr {
+	s, ok := f.pi.TypesInfo.TypeOf(list).Underlying().(*types.Slice)
+	if !ok {
+		errorf(f.pi, list, "slice field expected")
+	}
+
+	st, ok := s.Elem().Underlying().(*types.Struct)
+	if !ok {
+		errorf(f.pi, list, "only slices of structs are supported in the loop over the document field")
+	}
+
+	ef := *f
+	ef.doc, ef.docType = elem.Name, st
+
+	c := ef.parseIfCond(cond)
+
+	switch {
+	case expr.IsFalse(c):
+		return expr.False
+	case c.ClientEval || len(c.ListClient) > 0:
+		errorf(f.pi, cond, "condition on the fields of the loop element is expected")
+	}
+
+	return expr.NewElemMatch(x, c)
+}
+
+// loopMatchField returns the field compared to the loop variable
+// in the d.Field == v condition.
+func (f *funcParser) loopMatchField(cond ast.Expr, v *types.Var) ast.Expr {
+	be, ok := unparen(cond).(*ast.BinaryExpr)
+	if ok && be.Op == token.EQL {
+		isVar := func(e ast.Expr) bool {
+			id, ok := unparen(e).(*ast.Ident)
+			return ok && v != nil && f.pi.TypesInfo.Uses[id] == v
+		}
+
+		switch {
+		case isVar(be.Y) && !isVar(be.X):
+			return be.X
+		case isVar(be.X) && !isVar(be.Y):
+			return be.Y
+		}
+	}
+
+	errorf(f.pi, cond, "only equality of the document field and the loop variable is supported")
+
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"go/ast"
+	"go/token"
+
+	"github.com/tigrisdata/tigrisgen/expr"
+)
+
+// parseNilCmp translates comparison of the optional argument with nil
+// to the client side condition, so as the condition on the argument
+// can be omitted, when the argument is not set:
+//
+//	args.Name == nil || d.Name == *args.Name
+func (f *funcParser) parseNilCmp(e *ast.BinaryExpr) (expr.Expr, bool) {
+	if e.Op != token.EQL && e.Op != token.NEQ {
+		return expr.Expr{}, false
+	}
+
+	other := e.X
+
+	if !isNil(f.pi, e.Y) {
+		if !isNil(f.pi, e.X) {
+			return expr.Expr{}, false
+		}
+
+		other = e.Y
+	}
+
+	x := f.parseOperand(other)
+	if x.Type != expr.Arg {
+		return expr.Expr{}, false
+	}
+
+	return expr.NewExpr(expr.Eq, expr.NewPipeline("isNil "+x.ArgPath()), expr.NewConstant(e.Op == token.EQL)).Client(), true
+}
+
+// parseDerefOperand translates dereference of the optional argument.
+// Document field pointers are transparent for the filter.
+func (f *funcParser) parseDerefOperand(e *ast.StarExpr) expr.Operand {
+	x := f.parseOperand(e.X)
+	if x.Type == expr.Arg {
+		return expr.NewPipeline("deref " + x.ArgPath())
+	}
+
+	return x
+}
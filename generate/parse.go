@@ -22,21 +22,35 @@ import (
 	"go/constant"
 	"go/token"
 	"go/types"
-	"reflect"
 	"strings"
 
-	"github.com/rs/zerolog/log"
 	"github.com/tigrisdata/tigrisgen/expr"
 	"github.com/tigrisdata/tigrisgen/util"
 	"golang.org/x/tools/go/packages"
 )
 
 type funcParser struct {
+	*generator
+
 	doc      string
 	docType  *types.Struct
 	args     string
 	argsType *types.Struct
 	pi       *packages.Package
+
+	// function being parsed
+	fn *ast.FuncDecl
+	// variables captured by the closure, shared with the copies
+	// of the parser
+	captured *captures
+	// definitions of the local variables
+	locals map[*types.Var]ast.Expr
+	// conditions bound to the ok variables of comma-ok forms
+	conds map[*types.Var]expr.Expr
+	// operands bound to the parameters of the inlined function
+	params map[*types.Var]expr.Operand
+	// functions being inlined, used to detect recursion
+	inlined []string
 }
 
 func parseConst(v constant.Value) expr.Operand {
@@ -78,7 +92,7 @@ func (f *funcParser) parseTrueFalseOnly(e ast.Expr) expr.Expr {
 		}
 	}
 
-	FatalWithExpr(f.pi, e, "unsupported constant in unary operator")
+	errorf(f.pi, e, "unsupported constant in unary operator")
 	panic("unsupported constant in unary operator")
 }
 
@@ -95,10 +109,16 @@ L:
 			ex = e.X
 		case *ast.IndexExpr:
 			ex = e.X
+		case *ast.StarExpr:
+			ex = e.X
+			continue // pointer dereference is not a path element
+		case *ast.ParenExpr:
+			ex = e.X
+			continue
 		case *ast.Ident:
 			break L
 		default:
-			FatalWithExpr(f.pi, in, "unknown expr in selector")
+			errorf(f.pi, in, "unknown expr in selector")
 		}
 
 		cnt++
@@ -117,11 +137,22 @@ L:
 			if x.Type == expr.Constant {
 				path[cnt] = fmt.Sprintf("%v", x.Value)
 			} else if x.Type == expr.Arg {
-				path[cnt] = fmt.Sprintf("{{.Arg.%v}}", x.Value)
+				path[cnt] = fmt.Sprintf("{{%v}}", x.ArgPath())
 			}
 
 			in = e.X
+		case *ast.StarExpr:
+			in = e.X
+			cnt++
+		case *ast.ParenExpr:
+			in = e.X
+			cnt++
 		case *ast.Ident:
+			if def, ok := f.local(e); ok {
+				n, p := f.parseSelector(def)
+				return n, append(p, path...)
+			}
+
 			return e.Name, path
 		}
 	}
@@ -159,21 +190,25 @@ func toFieldName(tp *types.Struct, path []string) string {
 					sb.WriteString(".")
 				}
 
-				tag := strings.Split(reflect.StructTag(tp.Tag(i)).Get("json"), ",")
-				if tag[0] != "" {
-					sb.WriteString(tag[0])
+				if tag := jsonTag(tp, i); tag != "" {
+					sb.WriteString(tag)
 				} else {
 					sb.WriteString(tp.Field(i).Name())
 				}
 
-				if _, ok := tp.Field(i).Type().(*types.Named); ok {
-					tp, _ = tp.Field(i).Type().(*types.Named).Underlying().(*types.Struct)
-				} else if s, ok := tp.Field(i).Type().(*types.Slice); ok {
+				ft := tp.Field(i).Type()
+				if p, ok := ft.(*types.Pointer); ok {
+					ft = p.Elem()
+				}
+
+				if _, ok := ft.(*types.Named); ok {
+					tp, _ = ft.(*types.Named).Underlying().(*types.Struct)
+				} else if s, ok := ft.(*types.Slice); ok {
 					if _, ok = s.Elem().Underlying().(*types.Struct); ok {
 						tp, _ = s.Elem().Underlying().(*types.Struct)
 					}
 					mapOrArr = true
-				} else if s, ok := tp.Field(i).Type().(*types.Map); ok {
+				} else if s, ok := ft.(*types.Map); ok {
 					if _, ok = s.Elem().Underlying().(*types.Struct); ok {
 						tp, _ = s.Elem().Underlying().(*types.Struct)
 					}
@@ -188,19 +223,71 @@ func toFieldName(tp *types.Struct, path []string) string {
 	return sb.String()
 }
 
+// selectorRoots returns the names of the document and arguments
+// parameters, which can be used in the selectors.
+func (f *funcParser) selectorRoots() string {
+	var names []string
+
+	for _, v := range []string{f.doc, f.args} {
+		if v != "" && v != "_" {
+			names = append(names, v)
+		}
+	}
+
+	return strings.Join(names, " or ")
+}
+
 func (f *funcParser) parseOperand(node ast.Expr) expr.Operand {
-	log.Debug().Msg("parse operand")
+	f.log.Debug().Msg("parse operand")
+
+	if tv := f.pi.TypesInfo.Types[node]; tv.Value != nil {
+		f.checkConstMarshaler(node)
+
+		// the template compares the float operands with the float constants only
+		if isFloat(tv.Type) {
+			v, _ := constant.Float64Val(constant.ToFloat(tv.Value))
+			return expr.NewOperand(v, expr.Constant)
+		}
+
+		return parseConst(tv.Value)
+	}
 
-	if v := f.pi.TypesInfo.Types[node].Value; v != nil {
-		return parseConst(v)
+	if isNil(f.pi, node) {
+		return expr.NewConstant(nil)
+	}
+
+	if x, ok := f.foldOperand(node); ok {
+		return x
 	}
 
 	switch e := node.(type) {
+	case *ast.StarExpr:
+		return f.parseDerefOperand(e)
+	case *ast.ParenExpr:
+		return f.parseOperand(e.X)
+	case *ast.BinaryExpr, *ast.UnaryExpr:
+		if x, ok := f.parseArithOperand(e); ok {
+			return x
+		}
 	case *ast.SelectorExpr, *ast.IndexExpr:
 		n, path := f.parseSelector(e)
 
 		if n != f.doc && n != f.args {
-			util.Fatal("unsupported selector %+v, expected: %v or %v", n, f.doc, f.args)
+			if id := rootIdent(e); id != nil {
+				if x, ok := f.paramSelector(id, path); ok {
+					return x
+				}
+
+				if name, ok := f.capturedName(id); ok {
+					return expr.NewCaptured(name, strings.Join(path, "."))
+				}
+			}
+
+			if roots := f.selectorRoots(); roots != "" {
+				errorf(f.pi, e, "unsupported selector, expected: %v", roots)
+			}
+
+			errorf(f.pi, e, "unsupported selector")
 		}
 
 		if n == f.doc {
@@ -209,22 +296,60 @@ func (f *funcParser) parseOperand(node ast.Expr) expr.Operand {
 
 		return expr.NewOperand(strings.Join(path, "."), expr.Arg) // struct arg
 	case *ast.Ident:
+		if def, ok := f.local(e); ok {
+			return f.parseOperand(def)
+		}
+
+		if x, ok := f.param(e); ok {
+			return x
+		}
+
 		switch e.Name {
 		case f.args:
 			return expr.NewOperand("", expr.Arg) // simple arg
 		}
+
+		if name, ok := f.capturedName(e); ok {
+			return expr.NewCaptured(name, "")
+		}
 	case *ast.CallExpr:
+		if isConversion(f.pi, e) {
+			return f.parseConversion(e)
+		}
+
+		if x, ok := f.parseLenOperand(e); ok {
+			return x
+		}
+
+		if x, ok := f.parseTimeOperand(e); ok {
+			return x
+		}
+
+		if x, ok := f.parseArgCall(e); ok {
+			return x
+		}
+
 		ee := f.parseFuncCall(e)
 		if ee.Type == expr.FuncOp {
 			return expr.NewFunc(ee.X, ee.Y)
 		}
+	case *ast.TypeAssertExpr:
+		if x := f.parseOperand(e.X); x.Type == expr.Arg {
+			return x
+		}
 	}
 
-	FatalWithExpr(f.pi, node, "unsupported operand type")
+	errorf(f.pi, node, "unsupported operand type")
 
 	return expr.NewOperand(nil, 0) // unreachable
 }
 
+func (f *funcParser) validateOperands(x expr.Operand, y expr.Operand, e ast.Node) {
+	if err := expr.ValidateOperands(x, y); err != nil {
+		errorf(f.pi, e, "%v", err)
+	}
+}
+
 func (f *funcParser) parseUnaryNegation(e ast.Expr) expr.Expr {
 	if v := f.pi.TypesInfo.Types[e].Value; v != nil {
 		return expr.Negate(f.parseTrueFalseOnly(e))
@@ -241,6 +366,10 @@ func (f *funcParser) parseUnaryNegation(e ast.Expr) expr.Expr {
 			return expr.NewExpr(expr.Ne, x, expr.NewConstant(true)).Client()
 		}
 	case *ast.Ident:
+		if f.isLocal(ee) {
+			return expr.Negate(f.parseBinaryExprLow(ee))
+		}
+
 		x := f.parseOperand(ee)
 		if x.Type == expr.Arg {
 			return expr.NewExpr(expr.Ne, x, expr.NewConstant(true)).Client()
@@ -248,49 +377,65 @@ func (f *funcParser) parseUnaryNegation(e ast.Expr) expr.Expr {
 	case *ast.CallExpr:
 		x := f.parseFuncCall(ee)
 		return expr.Negate(x)
+	case *ast.ParenExpr:
+		return f.parseUnaryNegation(ee.X)
 	}
 
-	FatalWithExpr(f.pi, e, "unsupported unary operator")
+	errorf(f.pi, e, "unsupported unary operator")
 
 	return expr.Expr{} // unreachable
 }
 
 func (f *funcParser) parseFuncCall(e *ast.CallExpr) expr.Expr {
-	log.Debug().Msg("parse func call")
+	f.log.Debug().Msg("parse func call")
 
 	switch fn := e.Fun.(type) {
 	case *ast.SelectorExpr:
+		if sel, ok := f.pi.TypesInfo.Selections[fn]; ok && sel.Kind() == types.MethodVal &&
+			isRegexpType(sel.Recv()) && fn.Sel.Name == "MatchString" {
+			return f.patternOp(expr.Regex, e, e.Args[0], f.parseRegexpOperand(fn.X))
+		}
+
 		s, ok := fn.X.(*ast.Ident)
 		if !ok {
 			if len(e.Args) != 1 {
 				break
 			}
 
-			tt, ok := f.pi.TypesInfo.Types[fn.X].Type.(*types.Named)
+			xt := f.pi.TypesInfo.Types[fn.X].Type
+			if p, ok := xt.(*types.Pointer); ok {
+				xt = p.Elem()
+			}
+
+			tt, ok := xt.(*types.Named)
 			if ok && tt.String() == "time.Time" {
 				x := f.parseOperand(fn.X)
 				y := f.parseOperand(e.Args[0])
 
-				expr.ValidateOperands(x, y, nil)
+				f.validateOperands(x, y, e)
+
+				if c, ok := f.parseTimeArgCmp(fn.Sel.Name, x, y, e); ok {
+					return c
+				}
 
 				switch fn.Sel.Name {
 				case "After":
-					log.Debug().Str("op", string(expr.Gt)).
+					f.log.Debug().Str("op", string(expr.Gt)).
 						Interface("x", x.Value).Interface("y", y.Value).Msg("time.After")
 
 					return filterOp(expr.Gt, x, y)
 				case "Before":
-					log.Debug().Str("op", string(expr.Lt)).
+					f.log.Debug().Str("op", string(expr.Lt)).
 						Interface("x", x.Value).Interface("y", y.Value).Msg("time.After")
 
 					return filterOp(expr.Lt, x, y)
 				case "Equal":
-					log.Debug().Str("op", string(expr.Eq)).
+					f.log.Debug().Str("op", string(expr.Eq)).
 						Interface("x", x.Value).Interface("y", y.Value).Msg("time.After")
 
 					return filterOp(expr.Eq, x, y)
 				case "Compare":
-					log.Debug().Str("op", "Compare").
+					f.log.Debug().Str("op", "Compare").
 						Interface("x", x.Value).Interface("y", y.Value).Msg("time.After")
 
 					return expr.NewExpr(expr.FuncOp, x, y)
@@ -304,20 +449,36 @@ func (f *funcParser) parseFuncCall(e *ast.CallExpr) expr.Expr {
 			path := pkg.Imported().Path()
 			switch path {
 			case "strings":
-				if fn.Sel.Name == "Contains" {
+				switch fn.Sel.Name {
+				case "Contains":
 					x := f.parseOperand(e.Args[0])
 					y := f.parseOperand(e.Args[1])
-					expr.ValidateOperands(x, y, nil)
+					f.validateOperands(x, y, e)
 
 					return filterOp(expr.Contains, x, y)
+				case "HasPrefix":
+					return f.patternOp(expr.Prefix, e, e.Args[0], f.parsePattern(e.Args[1], false))
+				case "HasSuffix":
+					return f.patternOp(expr.Suffix, e, e.Args[0], f.parsePattern(e.Args[1], false))
+				}
+			case "slices", "golang.org/x/exp/slices":
+				if fn.Sel.Name == "Contains" {
+					return f.parseMembership(e, e.Args[1], f.parseListOperand(e.Args[0]))
 				}
 			case "bytes":
-				if fn.Sel.Name == "Compare" {
+				switch fn.Sel.Name {
+				case "Compare":
 					x := f.parseOperand(e.Args[0])
 					y := f.parseOperand(e.Args[1])
-					expr.ValidateOperands(x, y, nil)
+					f.validateOperands(x, y, e)
 
 					return expr.NewExpr(expr.FuncOp, x, y) // this is further processed in filterOp
+				case "Equal":
+					x := f.parseOperand(e.Args[0])
+					y := f.parseOperand(e.Args[1])
+					f.validateOperands(x, y, e)
+
+					return filterOp(expr.Eq, x, y)
 				}
 			case "time":
 				switch fn.Sel.Name {
@@ -331,7 +492,7 @@ func (f *funcParser) parseFuncCall(e *ast.CallExpr) expr.Expr {
 		if fn.Name == "append" {
 			x := f.parseOperand(e.Args[0])
 			y := f.parseOperand(e.Args[1])
-			expr.ValidateOperands(x, y, nil)
+			f.validateOperands(x, y, e)
 
 			if x.Type == expr.Field && (y.Type == expr.Constant || y.Type == expr.Arg) {
 				return expr.NewExpr(expr.PushOp, x, y)
@@ -339,7 +500,11 @@ func (f *funcParser) parseFuncCall(e *ast.CallExpr) expr.Expr {
 		}
 	}
 
-	FatalWithExpr(f.pi, e, "unsupported function call")
+	if c, ok := f.parseInlineCall(e); ok {
+		return c
+	}
+
+	errorf(f.pi, e, "unsupported function call %v", types.ExprString(e.Fun))
 
 	return expr.Expr{}
 }
@@ -372,7 +537,23 @@ func filterOp(op expr.Op, x expr.Operand, y expr.Operand) expr.Expr {
 func (f *funcParser) parseBinaryExprLow(node ast.Node) expr.Expr {
 	switch e := node.(type) {
 	case *ast.BinaryExpr:
-		log.Debug().Str("op", e.Op.String()).Msg("parse binary expression")
+		f.log.Debug().Str("op", e.Op.String()).Msg("parse binary expression")
+
+		if c, ok := f.parseLenCmp(e); ok {
+			return c
+		}
+
+		if c, ok := f.parseTimeCmp(e); ok {
+			return c
+		}
+
+		if c, ok := f.parseNilCmp(e); ok {
+			return c
+		}
+
+		if c, ok := f.parseStructCmp(e); ok {
+			return c
+		}
 
 		switch e.Op {
 		case token.LAND:
@@ -388,47 +569,55 @@ func (f *funcParser) parseBinaryExprLow(node ast.Node) expr.Expr {
 		case token.GEQ:
 			x := f.parseOperand(e.X)
 			y := f.parseOperand(e.Y)
-			expr.ValidateOperands(x, y, e)
+			f.validateOperands(x, y, e)
 
 			return filterOp(expr.Gte, x, y)
 		case token.LEQ:
 			x := f.parseOperand(e.X)
 			y := f.parseOperand(e.Y)
-			expr.ValidateOperands(x, y, e)
+			f.validateOperands(x, y, e)
 
 			return filterOp(expr.Lte, x, y)
 		case token.LSS:
 			x := f.parseOperand(e.X)
 			y := f.parseOperand(e.Y)
-			expr.ValidateOperands(x, y, e)
+			f.validateOperands(x, y, e)
 
 			return filterOp(expr.Lt, x, y)
 		case token.GTR:
 			x := f.parseOperand(e.X)
 			y := f.parseOperand(e.Y)
-			expr.ValidateOperands(x, y, e)
+			f.validateOperands(x, y, e)
 
 			return filterOp(expr.Gt, x, y)
 		case token.EQL:
 			x := f.parseOperand(e.X)
 			y := f.parseOperand(e.Y)
-			expr.ValidateOperands(x, y, e)
+			f.validateOperands(x, y, e)
 
 			return filterOp(expr.Eq, x, y)
 		case token.NEQ:
 			x := f.parseOperand(e.X)
 			y := f.parseOperand(e.Y)
-			expr.ValidateOperands(x, y, e)
+			f.validateOperands(x, y, e)
 
 			return filterOp(expr.Ne, x, y)
 		default:
-			util.Fatal("unsupported binary op: %v", e.Op.String())
+			errorf(f.pi, e, "unsupported binary op: %v", e.Op.String())
 		}
 	case *ast.UnaryExpr:
 		if e.Op == token.NOT {
 			return f.parseUnaryNegation(e.X)
 		}
 	case *ast.Ident: // only true | false idents supported
+		if c, ok := f.localCond(e); ok {
+			return c
+		}
+
+		if def, ok := f.local(e); ok {
+			return f.parseBinaryExprLow(def)
+		}
+
 		x := f.parseOperand(e)
 		if x.Type == expr.Arg {
 			return expr.NewExpr(expr.Eq, x, expr.NewConstant(true)).Client()
@@ -452,7 +641,7 @@ func (f *funcParser) parseBinaryExprLow(node ast.Node) expr.Expr {
 		return f.parseFuncCall(e)
 	}
 
-	FatalWithExpr(f.pi, node, "unexpected binary expression")
+	errorf(f.pi, node, "unexpected binary expression")
 	panic("unexpected expression")
 }
 
@@ -461,16 +650,20 @@ func (f *funcParser) parseBinaryExpr(expr *ast.BinaryExpr) expr.Expr {
 }
 
 func (f *funcParser) parseReturnStatement(stmt *ast.ReturnStmt) expr.Expr {
-	log.Debug().Msg("parseReturnStatement")
+	f.log.Debug().Msg("parseReturnStatement")
 
 	if len(stmt.Results) != 1 {
-		util.Fatal("Only one bool result is allowed in return")
+		errorf(f.pi, stmt, "Only one bool result is allowed in return")
 	}
 
 	switch e := stmt.Results[0].(type) {
 	case *ast.BinaryExpr:
 		return f.parseBinaryExpr(e)
 	case *ast.Ident:
+		if f.isLocal(e) {
+			return f.parseBinaryExprLow(e)
+		}
+
 		x := f.parseOperand(e)
 		if x.Type == expr.Field {
 			return expr.NewExpr(expr.Eq, x, expr.NewConstant(true))
@@ -484,13 +677,13 @@ func (f *funcParser) parseReturnStatement(stmt *ast.ReturnStmt) expr.Expr {
 			}
 		}
 
-		util.Fatal("unsupported return variable: %+v", e)
+		errorf(f.pi, e, "unsupported return variable")
 	case *ast.SelectorExpr:
 		x := f.parseOperand(e)
 		if x.Type == expr.Field {
 			return expr.NewExpr(expr.Eq, x, expr.NewConstant(true))
 		} else {
-			util.Fatal("unsupported return variable: %+v", e)
+			errorf(f.pi, e, "unsupported return variable")
 		}
 	case *ast.UnaryExpr:
 		if e.Op == token.NOT {
@@ -499,7 +692,7 @@ func (f *funcParser) parseReturnStatement(stmt *ast.ReturnStmt) expr.Expr {
 	case *ast.CallExpr:
 		return f.parseFuncCall(e)
 	default:
-		FatalWithExpr(f.pi, e, "return should be a logical expression")
+		errorf(f.pi, e, "return should be a logical expression")
 	}
 
 	panic("unsupported return statement")
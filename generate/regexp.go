@@ -0,0 +1,121 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"go/ast"
+	"go/types"
+	"regexp"
+
+	"github.com/tigrisdata/tigrisgen/expr"
+)
+
+// isRegexpType returns true for the *regexp.Regexp type.
+func isRegexpType(tp types.Type) bool {
+	p, ok := tp.(*types.Pointer)
+	if !ok {
+		return false
+	}
+
+	n, ok := p.Elem().(*types.Named)
+
+	return ok && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == "regexp" && n.Obj().Name() == "Regexp"
+}
+
+// isRegexpFunc returns true if the call is the call of the function
+// of the regexp package with the given name.
+func (f *funcParser) isRegexpFunc(e ast.Expr, name string) bool {
+	ce, ok := unparen(e).(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+
+	var id *ast.Ident
+
+	switch fn := ce.Fun.(type) {
+	case *ast.Ident:
+		id = fn
+	case *ast.SelectorExpr:
+		id = fn.Sel
+	default:
+		return false
+	}
+
+	fn, ok := f.pi.TypesInfo.Uses[id].(*types.Func)
+
+	return ok && fn.Pkg() != nil && fn.Pkg().Path() == "regexp" && fn.Name() == name
+}
+
+// parseRegexpOperand returns the pattern of the compiled regular expression.
+// The expression is either regexp.MustCompile call or the variable
+// initialized by it.
+func (f *funcParser) parseRegexpOperand(e ast.Expr) expr.Operand {
+	if f.isRegexpFunc(e, "MustCompile") {
+		return f.parsePattern(unparen(e).(*ast.CallExpr).Args[0], true)
+	}
+
+	v := exprVar(f.pi, e)
+	if v == nil {
+		errorf(f.pi, e, "regexp.MustCompile call or variable expected")
+	}
+
+	if def, ok := f.locals[v]; ok {
+		return f.parseRegexpOperand(def)
+	}
+
+	vals, reason := f.varValues(f.pi, v, make(map[*types.Var]bool))
+	if reason != "" {
+		errorf(f.pi, e, "can't determine the regular expression: %v", reason)
+	}
+
+	if len(vals) != 1 {
+		errorf(f.pi, e, "can't determine the regular expression: '%v' is assigned more than once", v.Name())
+	}
+
+	return f.parseRegexpOperand(vals[0])
+}
+
+// parsePattern parses the prefix, suffix or regular expression pattern,
+// constant regular expressions are validated.
+func (f *funcParser) parsePattern(e ast.Expr, isRegexp bool) expr.Operand {
+	p := f.parseOperand(e)
+
+	switch p.Type {
+	case expr.Constant:
+		s, ok := p.Value.(string)
+		if !ok {
+			errorf(f.pi, e, "string pattern expected")
+		}
+
+		if _, err := regexp.Compile(s); isRegexp && err != nil {
+			errorf(f.pi, e, "invalid regular expression: %v", err)
+		}
+	case expr.Arg:
+	default:
+		errorf(f.pi, e, "constant or argument pattern expected")
+	}
+
+	return p
+}
+
+// patternOp builds the match of the document field against the pattern.
+func (f *funcParser) patternOp(op expr.Op, e *ast.CallExpr, field ast.Expr, pattern expr.Operand) expr.Expr {
+	x := f.parseOperand(field)
+	if x.Type != expr.Field {
+		errorf(f.pi, e, "document field is expected as the matched string")
+	}
+
+	return expr.NewExpr(op, x, pattern)
+}
@@ -0,0 +1,200 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"bytes"
+	"go/ast"
+	"math"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// renderFilter translates the test filter function and renders
+// the result with the template functions of the generated file.
+func renderFilter(t *testing.T, name string, args Args) (string, error) {
+	t.Helper()
+
+	for _, pi := range testGen.program {
+		for _, f := range pi.Syntax {
+			for _, v := range f.Decls {
+				fn, ok := v.(*ast.FuncDecl)
+				if !ok || fn.Name.Name != name {
+					continue
+				}
+
+				flt, err := testGen.parseFilterFunction(fn.Name.Name, fn, pi)
+				require.NoError(t, err)
+
+				tmpl, err := template.New(name).Funcs(tigrisFuncs).Parse(flt.Body)
+				require.NoError(t, err)
+
+				var buf bytes.Buffer
+
+				err = tmpl.Execute(&buf, map[string]any{"Arg": args})
+
+				return buf.String(), err
+			}
+		}
+	}
+
+	require.Failf(t, "test function not found", "%v", name)
+
+	return "", nil
+}
+
+func TestRenderFilter(t *testing.T) {
+	str := "a"
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name string
+		args Args
+		exp  string
+		err  string
+	}{
+		{
+			name: "parseTest_arith_args",
+			args: Args{ArgFloat: 1.25, ArgInt: 2, ArgInt64: 10},
+			exp:  `{"$and":[{"field_float":{"$gt":2.5}},{"field_int":{"$gte":3}},{"field_int64":{"$lt":-3}}]}`,
+		},
+		{
+			name: "parseTest_arith_conversion",
+			args: Args{ArgInt32: 3, ArgUint64: math.MaxUint64 - 1},
+			exp:  `{"$and":[{"field_float":{"$gt":1.5}},{"field_uint64":{"$lt":18446744073709551615}}]}`,
+		},
+		{
+			name: "parseTest_arg_call_conversion",
+			args: Args{ArgStatus: "ACTIVE", ArgInt32: 7},
+			exp:  `{"$and":[{"field_string":"active"},{"nested.field_string":{"$ne":"8"}}]}`,
+		},
+		{
+			name: "parseTestClientEval_or_guarded",
+			args: Args{ArgInt: 5},
+			exp:  `{"$or":[{"field_int":5}]}`,
+		},
+		{
+			name: "parseTestClientEval_or_guarded",
+		},
+		{
+			name: "parseTestClientEval_or_guarded_nested",
+			exp:  `{"$or":[{"field_int":0}]}`,
+		},
+		{
+			name: "parseTestClientEval_or_guarded_nested",
+			args: Args{ArgPtrStr: &str},
+			exp:  `{"$or":[{"field_int":0},{"$and":[{"field_float":1},{"$or":[{"field_string":"a"}]}]}]}`,
+		},
+		{
+			name: "parseTestClientEval_arg_cmp_float",
+			args: Args{ArgInt32: 3, ArgFloat: 2},
+			exp:  `{}`,
+		},
+		{
+			name: "parseTestClientEval_arg_cmp_float",
+			args: Args{ArgInt32: 3, ArgFloat: 1.5},
+			exp:  `{"field_float":{"$gt":3}}`,
+		},
+		{
+			name: "parseTestClientEval_optional_float",
+			args: Args{ArgFloat: 1.5},
+			exp:  `{"$or":[{"field_float":{"$gt":1.5}}]}`,
+		},
+		{
+			name: "parseTestClientEval_optional_float",
+			args: Args{ArgFloat: 2},
+		},
+		{
+			name: "parseTestClientEval_optional_float",
+			args: Args{ArgFloat: 1e22},
+			exp:  `{"$or":[{"field_float":{"$gt":1e22}},{"$and":[{"field_float":{"$lte":1e22}},{"field_int":0}]}]}`,
+		},
+		{
+			name: "parseTestClientEval_arg_cmp_time",
+			args: Args{ArgTime: now, NestedArg: NestedArg{ArgTime: now}},
+			exp:  `{"field_time":{"$gt":"2023-01-01T00:00:00Z"}}`,
+		},
+		{
+			name: "parseTestClientEval_arg_cmp_time",
+			args: Args{ArgTime: now, NestedArg: NestedArg{ArgTime: now.Add(time.Hour)}},
+		},
+		{
+			name: "parseTest_len_array_ge_lt_arg",
+			args: Args{ArgInt: 2},
+			exp:  `{"$and":[{"field_arr.1":{"$exists":true}},{"field_arr_float.1":{"$exists":false}}]}`,
+		},
+		{
+			name: "parseTest_len_array_le_arg",
+			args: Args{ArgInt: -1},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			res, err := renderFilter(t, c.name, c.args)
+			if c.err != "" {
+				assert.ErrorContains(t, err, c.err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			// empty filter doesn't match any document
+			if c.exp == "" {
+				assert.Empty(t, res)
+				return
+			}
+
+			assert.JSONEq(t, c.exp, res)
+		})
+	}
+}
+
+func TestTigrisArith(t *testing.T) {
+	cases := []struct {
+		op  string
+		x   any
+		y   any
+		exp any
+		err string
+	}{
+		{"+", int32(3), 4, int32(7), ""},
+		{"/", 3, 2, 1, ""},
+		{"/", 3.0, 2, 1.5, ""},
+		{"/", 3, 0, nil, "integer divide by zero"},
+		{"+", uint64(math.MaxUint64 - 1), 1, uint64(math.MaxUint64), ""},
+		{"/", uint64(math.MaxUint64), 2, uint64(math.MaxUint64 / 2), ""},
+		{"-", 0, uint64(1), uint64(math.MaxUint64), ""},
+		{"-", 0, int64(1), int64(-1), ""},
+		{"+", "a", "b", "ab", ""},
+		{"-", "a", "b", nil, "unsupported operands of -: a, b"},
+		{"%", 1.5, 1, nil, "unsupported operation % on 1.5, 1"},
+	}
+
+	for _, c := range cases {
+		res, err := tigrisArith(c.op, c.x, c.y)
+		if c.err != "" {
+			assert.EqualError(t, err, c.err)
+			continue
+		}
+
+		require.NoError(t, err)
+		assert.Equal(t, c.exp, res, "%v %v %v", c.x, c.op, c.y)
+	}
+}
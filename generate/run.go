@@ -0,0 +1,265 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/tools/go/packages"
+)
+
+const (
+	DefaultAPIPackage = "github.com/tigrisdata/tigris-client-go/tigris"
+	GenFileName       = "tigris.gen.go"
+)
+
+// Config of the generation run.
+type Config struct {
+	// Patterns of the packages to process, in the format of "go list".
+	// Defaults to the package in the Dir.
+	Patterns []string
+	// Dir is the directory to load packages from.
+	// Defaults to the current directory.
+	Dir string
+	// PackageName restricts processing to the packages with the given name.
+	// All packages matching the Patterns are processed if empty.
+	PackageName string
+	// OutputDir is the directory to write generated file to.
+	// Generated file is written to the package directory if empty.
+	// Should only be set when Patterns match single package.
+	OutputDir string
+	// APIPackage is the import path of the Tigris API package.
+	// Defaults to DefaultAPIPackage.
+	APIPackage string
+	// APIs are additional functions which take filter and update
+	// functions as parameters, usually read from tigrisgen.yaml.
+	APIs []APIPackage
+	// Log receives the log output. Logging is disabled if nil.
+	Log *zerolog.Logger
+}
+
+// PackageResult contains filters and updates generated for the package.
+type PackageResult struct {
+	ID       string
+	Name     string
+	PkgPath  string
+	FileName string // generated file, empty if not written
+
+	Filters     []FilterDef
+	Updates     []FilterDef
+	Diagnostics Diagnostics
+}
+
+// Result of the generation run.
+type Result struct {
+	Packages []*PackageResult
+}
+
+// Diagnostics returns diagnostics of all the processed packages.
+func (r *Result) Diagnostics() Diagnostics {
+	if r == nil {
+		return nil
+	}
+
+	var l Diagnostics
+
+	for _, v := range r.Packages {
+		l = append(l, v.Diagnostics...)
+	}
+
+	return l
+}
+
+// generator holds the state of one generation run.
+type generator struct {
+	apis    apiCatalog
+	log     zerolog.Logger
+	loadCfg packages.Config
+
+	// program source loaded into memory
+	program map[string]*packages.Package
+	// assignments of variables per package, built lazily
+	assigns map[*packages.Package]*assignIndex
+	// packages scanned for API wrappers
+	scanned map[*packages.Package]bool
+	// function arguments forwarded by API wrappers
+	forwarded map[ast.Expr]bool
+}
+
+func newGenerator(ctx context.Context, cfg *Config) *generator {
+	apiPkg := cfg.APIPackage
+	if apiPkg == "" {
+		apiPkg = DefaultAPIPackage
+	}
+
+	g := &generator{
+		apis:      newAPICatalog(apiPkg, cfg.APIs),
+		log:       zerolog.Nop(),
+		loadCfg:   packages.Config{Context: ctx, Dir: cfg.Dir},
+		program:   make(map[string]*packages.Package),
+		assigns:   make(map[*packages.Package]*assignIndex),
+		scanned:   make(map[*packages.Package]bool),
+		forwarded: make(map[ast.Expr]bool),
+	}
+
+	if cfg.Log != nil {
+		g.log = *cfg.Log
+	}
+
+	return g
+}
+
+// isTestMain detects the synthesized main package of the test binary.
+func isTestMain(pi *packages.Package) bool {
+	return pi.Name == "main" && strings.HasSuffix(pi.PkgPath, ".test")
+}
+
+// pkgDir returns the directory of the package source files.
+func pkgDir(pi *packages.Package) string {
+	for _, f := range pi.Syntax {
+		if n := pi.Fset.File(f.Pos()).Name(); !strings.HasSuffix(n, "_test.go") {
+			return filepath.Dir(n)
+		}
+	}
+
+	if len(pi.Syntax) > 0 {
+		return filepath.Dir(pi.Fset.File(pi.Syntax[0].Pos()).Name())
+	}
+
+	return ""
+}
+
+// Run finds Tigris API calls in the packages matching the patterns
+// and generates filters and updates for the functions passed to them.
+// The generated file is written for every package without errors.
+// The error is returned if there were errors in any of the packages,
+// the result contains diagnostics per package in this case.
+func Run(ctx context.Context, cfg Config) (*Result, error) {
+	if err := validateAPIs(cfg.APIs); err != nil {
+		return nil, err
+	}
+
+	g := newGenerator(ctx, &cfg)
+
+	patterns := cfg.Patterns
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	pkgs, err := g.loadProgram(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].ID < pkgs[j].ID })
+
+	res := &Result{}
+
+	for _, pi := range pkgs {
+		if cfg.PackageName != "" && pi.Name != cfg.PackageName || isTestMain(pi) {
+			continue
+		}
+
+		g.log.Debug().Str("package", pi.Name).Msg("processing")
+
+		flts, upds, d := g.findAndParse(pi)
+
+		res.Packages = append(res.Packages, &PackageResult{
+			ID: pi.ID, Name: pi.Name, PkgPath: pi.PkgPath,
+			Filters: flts, Updates: upds, Diagnostics: d,
+		})
+
+		if !d.HasErrors() && len(flts)+len(upds) > 0 {
+			dir := cfg.OutputDir
+			if dir == "" {
+				dir = pkgDir(pi)
+			}
+
+			res.Packages[len(res.Packages)-1].FileName = filepath.Join(dir, GenFileName)
+		}
+	}
+
+	if err = g.writeFiles(res); err != nil {
+		return res, err
+	}
+
+	return res, res.Diagnostics().Err()
+}
+
+// writeFiles writes generated files of the result.
+// Test variant of the package and the package itself share the
+// same generated file, so their filters and updates are merged.
+func (g *generator) writeFiles(res *Result) error {
+	type genFile struct {
+		pkg     string
+		filters []FilterDef
+		updates []FilterDef
+		names   map[string]bool
+	}
+
+	var (
+		files = make(map[string]*genFile)
+		order []string
+	)
+
+	for _, p := range res.Packages {
+		if p.FileName == "" {
+			continue
+		}
+
+		pkg := strings.TrimSuffix(p.Name, "_test")
+
+		f := files[p.FileName]
+		if f == nil {
+			f = &genFile{pkg: pkg, names: make(map[string]bool)}
+			files[p.FileName] = f
+			order = append(order, p.FileName)
+		} else if f.pkg != pkg {
+			return fmt.Errorf("packages '%v' and '%v' generate the same file %v", f.pkg, pkg, p.FileName)
+		}
+
+		for _, v := range p.Filters {
+			if !f.names["f"+v.Name] {
+				f.names["f"+v.Name] = true
+				f.filters = append(f.filters, v)
+			}
+		}
+
+		for _, v := range p.Updates {
+			if !f.names["u"+v.Name] {
+				f.names["u"+v.Name] = true
+				f.updates = append(f.updates, v)
+			}
+		}
+	}
+
+	for _, name := range order {
+		f := files[name]
+
+		g.log.Debug().Interface("filters", f.filters).Interface("updates", f.updates).Msg("parsed")
+
+		if err := g.writeGenFile(name, f.pkg, f.filters, f.updates); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package callforms contains API calls in the different syntactic forms.
+// See TestAPICallForms.
+package callforms
+
+import (
+	"context"
+
+	. "github.com/tigrisdata/tigrisgen/test" //nolint:revive,stylecheck
+)
+
+type Item struct {
+	Field int `json:"field"`
+}
+
+type Params struct {
+	Value int
+}
+
+func FilterDot(d Item, a Params) bool {
+	return d.Field == a.Value
+}
+
+func FilterFuncValue(d Item, a Params) bool {
+	return d.Field > a.Value
+}
+
+func FilterReassigned(d Item, a Params) bool {
+	return d.Field >= a.Value
+}
+
+func FilterMethodValue(d Item, a Item) bool {
+	return d.Field < a.Field
+}
+
+func FilterSkipped(d Item, a Params) bool {
+	return d.Field != a.Value
+}
+
+type readFunc func(context.Context, *NativeCollection[Item, Item], func(Item, Params) bool, Params) (*Response, error)
+
+func Calls(ctx context.Context, read readFunc, all bool) {
+	c := &NativeCollection[Item, Item]{}
+
+	_, _ = ReadOne(ctx, c, FilterDot, Params{})
+
+	rd := Read[Item, Item, Params]
+	_, _ = rd(ctx, c, FilterFuncValue, Params{})
+
+	del := DeleteOne[Item, Item, Params]
+	if all {
+		del = Delete[Item, Item, Params]
+	}
+
+	_, _ = del(ctx, c, FilterReassigned, Params{})
+
+	match := c.Match
+	_, _ = match(FilterMethodValue, Item{})
+
+	// not resolvable statically
+	_, _ = read(ctx, c, FilterSkipped, Params{})
+}
@@ -0,0 +1,77 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package closures contains closures passed to the API.
+// See TestClosures.
+package closures
+
+import (
+	"context"
+
+	"github.com/tigrisdata/tigrisgen/test"
+)
+
+type Item struct {
+	Field int `json:"field"`
+}
+
+type Params struct {
+	Value int
+}
+
+type Limits struct {
+	Max int
+}
+
+type Repo struct {
+	c *test.NativeCollection[Item, Item]
+}
+
+var filterGlobal = func(d Item, a Params) bool {
+	return d.Field != a.Value
+}
+
+func Calls(ctx context.Context, c *test.NativeCollection[Item, Item], min int) {
+	_, _ = test.Read(ctx, c, func(d Item, a Params) bool {
+		return d.Field == a.Value
+	}, Params{})
+
+	_, _ = test.Read(ctx, c, func(d Item, a Params) bool {
+		return d.Field > min
+	}, Params{})
+
+	lim := Limits{Max: 10}
+
+	_, _ = test.Update(ctx, c, func(d Item, a Params) bool {
+		return d.Field < lim.Max && d.Field != a.Value
+	}, func(d Item, v int) {
+		d.Field = lim.Max
+	}, Params{}, 1)
+
+	read := func() {
+		_, _ = test.Read(ctx, c, func(d Item, a Params) bool {
+			return d.Field >= min
+		}, Params{})
+	}
+
+	read()
+
+	_, _ = test.Read(ctx, c, filterGlobal, Params{})
+}
+
+func (r *Repo) Find(ctx context.Context) {
+	_, _ = test.Read(ctx, r.c, func(d Item, a Params) bool {
+		return d.Field <= a.Value
+	}, Params{})
+}
@@ -0,0 +1,101 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package funcvalues contains filters passed through variables and struct fields.
+// See TestFuncValues.
+package funcvalues
+
+import (
+	"context"
+
+	"github.com/tigrisdata/tigrisgen/test"
+)
+
+type Item struct {
+	Field int `json:"field"`
+}
+
+type Params struct {
+	Value int
+}
+
+// Query is exported, so its fields can be assigned by the importing packages.
+type Query struct {
+	Filter func(Item, Params) bool
+}
+
+// DefaultFilter can be assigned by the importing packages.
+var DefaultFilter = FilterActive
+
+type query struct {
+	filter func(Item, Params) bool
+	update func(Item, int)
+}
+
+func FilterActive(d Item, a Params) bool {
+	return d.Field == a.Value
+}
+
+func FilterAll(d Item, a Params) bool {
+	return d.Field >= a.Value
+}
+
+func FilterField(d Item, a Params) bool {
+	return d.Field < a.Value
+}
+
+func UpdateField(d Item, v int) {
+	d.Field = v
+}
+
+func pick(all bool) func(Item, Params) bool {
+	if all {
+		return FilterAll
+	}
+
+	return FilterActive
+}
+
+func Calls(ctx context.Context, c *test.NativeCollection[Item, Item], admin bool) {
+	f := FilterActive
+	if admin {
+		f = FilterAll
+	}
+
+	_, _ = test.Read(ctx, c, f, Params{})
+
+	var q query
+	q.filter = FilterField
+
+	if admin {
+		q = query{filter: nil, update: UpdateField}
+	}
+
+	_, _ = test.Update(ctx, c, q.filter, q.update, Params{}, 1)
+
+	p := pick(admin)
+	_, _ = test.Delete(ctx, c, p, Params{})
+
+	e := Query{Filter: FilterAll}
+	_, _ = test.DeleteOne(ctx, c, e.Filter, Params{})
+
+	_, _ = test.ReadOne(ctx, c, DefaultFilter, Params{})
+
+	// the assignments are not ordered, so all the values assigned
+	// to 'r' are considered, even the overwritten ones
+	r := pick(admin)
+	r = FilterField
+
+	_, _ = test.Read(ctx, c, r, Params{})
+}
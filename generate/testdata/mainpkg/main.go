@@ -0,0 +1,50 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command mainpkg contains API calls in the main package, which functions
+// are reported by the runtime as main.Name. See TestMainPackage.
+package main
+
+import (
+	"context"
+
+	"github.com/tigrisdata/tigrisgen/test"
+)
+
+type Item struct {
+	Field int `json:"field"`
+}
+
+type Params struct {
+	Value int
+}
+
+func FilterMain(d Item, a Params) bool {
+	return d.Field == a.Value
+}
+
+func (d Item) Greater(a Params) bool {
+	return d.Field > a.Value
+}
+
+func main() {
+	ctx := context.Background()
+	c := &test.NativeCollection[Item, Item]{}
+
+	_, _ = test.Read(ctx, c, FilterMain, Params{})
+	_, _ = test.Read(ctx, c, Item.Greater, Params{})
+	_, _ = test.Read(ctx, c, func(d Item, a Params) bool {
+		return d.Field < a.Value
+	}, Params{})
+}
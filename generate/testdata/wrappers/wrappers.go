@@ -0,0 +1,82 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package wrappers contains API calls through the user defined wrappers.
+// See TestAPIWrappers.
+package wrappers
+
+import (
+	"context"
+
+	"github.com/tigrisdata/tigrisgen/test"
+)
+
+type Item struct {
+	Field int `json:"field"`
+}
+
+type Params struct {
+	Value int
+}
+
+type Repo struct {
+	c *test.NativeCollection[Item, Item]
+}
+
+func (r *Repo) Find(ctx context.Context, f func(Item, Params) bool, a Params) error {
+	_, err := test.Read(ctx, r.c, f, a)
+	return err
+}
+
+func (r *Repo) Modify(ctx context.Context, f func(Item, Params) bool, u func(Item, int), a Params, v int) error {
+	_, err := test.Update(ctx, r.c, f, u, a, v)
+	return err
+}
+
+// FindActive wraps the wrapper.
+func FindActive(ctx context.Context, r *Repo, a Params, f func(Item, Params) bool) error {
+	return r.Find(ctx, f, a)
+}
+
+func DeleteAll[T any, F any](ctx context.Context, c *test.NativeCollection[T, T], f func(T, F) bool, a F) error {
+	_, err := test.Delete(ctx, c, f, a)
+	return err
+}
+
+func FilterFind(d Item, a Params) bool {
+	return d.Field == a.Value
+}
+
+func FilterModify(d Item, a Params) bool {
+	return d.Field > a.Value
+}
+
+func UpdateModify(d Item, v int) {
+	d.Field = v
+}
+
+func FilterActive(d Item, a Params) bool {
+	return d.Field != a.Value
+}
+
+func FilterDelete(d Item, a Params) bool {
+	return d.Field < a.Value
+}
+
+func Calls(ctx context.Context, r *Repo) {
+	_ = r.Find(ctx, FilterFind, Params{})
+	_ = r.Modify(ctx, FilterModify, UpdateModify, Params{}, 1)
+	_ = FindActive(ctx, r, Params{}, FilterActive)
+	_ = DeleteAll(ctx, r.c, FilterDelete, Params{})
+}
@@ -0,0 +1,176 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"github.com/tigrisdata/tigrisgen/expr"
+)
+
+func isTimeType(tp types.Type) bool {
+	n, ok := tp.(*types.Named)
+
+	return ok && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == "time" && n.Obj().Name() == "Time"
+}
+
+// timeFunc returns the name of the function of the time package called by the expression.
+func (f *funcParser) timeFunc(e *ast.CallExpr) string {
+	sel, ok := unparen(e.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+
+	fn, ok := f.pi.TypesInfo.Uses[sel.Sel].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != "time" {
+		return ""
+	}
+
+	return fn.Name()
+}
+
+// parseTimeOperand translates the time computed at query time,
+// like time.Now().Add(-time.Hour), to the template pipeline.
+func (f *funcParser) parseTimeOperand(e *ast.CallExpr) (expr.Operand, bool) {
+	if !isTimeType(f.pi.TypesInfo.TypeOf(e)) {
+		return expr.Operand{}, false
+	}
+
+	name := f.timeFunc(e)
+
+	switch name {
+	case "Now":
+		return expr.NewPipeline("now"), true
+	case "Add", "AddDate":
+		sel := unparen(e.Fun).(*ast.SelectorExpr)
+
+		t := f.parseOperand(sel.X)
+		if t.Type != expr.Arg {
+			errorf(f.pi, e, "time arithmetic is only supported on arguments and time.Now()")
+		}
+
+		args := make([]string, 0, len(e.Args)+2)
+		args = append(args, "time"+name, t.ArgPath())
+
+		for _, a := range e.Args {
+			args = append(args, f.tmplValue(f.parseOperand(a), a))
+		}
+
+		return expr.NewPipeline(strings.Join(args, " ")), true
+	}
+
+	return expr.Operand{}, false
+}
+
+// parseTimeArgCmp translates the comparison method of time.Time called on
+// the arguments or constants. The template can't compare the times by the
+// builtin functions, so the result of the timeCmp helper, which is the same
+// as of the Compare method, is compared with zero instead.
+func (f *funcParser) parseTimeArgCmp(method string, x expr.Operand, y expr.Operand, e ast.Expr) (expr.Expr, bool) {
+	if x.Type == expr.Field || y.Type == expr.Field {
+		return expr.Expr{}, false
+	}
+
+	var op expr.Op
+
+	switch method {
+	case "After":
+		op = expr.Gt
+	case "Before":
+		op = expr.Lt
+	case "Equal":
+		op = expr.Eq
+	case "Compare":
+	default:
+		return expr.Expr{}, false
+	}
+
+	c := expr.NewPipeline("timeCmp " + f.tmplValue(x, e) + " " + f.tmplValue(y, e))
+	zero := expr.NewConstant(int64(0))
+
+	if op == "" {
+		return expr.NewExpr(expr.FuncOp, c, zero), true // compared with the constant by filterOp
+	}
+
+	return expr.NewExpr(op, c, zero).Client(), true
+}
+
+// parseTimeCmp rewrites comparison of the duration since or until
+// the document field time into the bound of the field:
+//
+//	time.Since(d.T) < x  =>  d.T > now - x
+//	time.Until(d.T) < x  =>  d.T < now + x
+func (f *funcParser) parseTimeCmp(e *ast.BinaryExpr) (expr.Expr, bool) {
+	op, call, other := e.Op, unparen(e.X), e.Y
+
+	ce, ok := call.(*ast.CallExpr)
+	if !ok || (f.timeFunc(ce) != "Since" && f.timeFunc(ce) != "Until") {
+		if ce, ok = unparen(e.Y).(*ast.CallExpr); !ok || (f.timeFunc(ce) != "Since" && f.timeFunc(ce) != "Until") {
+			return expr.Expr{}, false
+		}
+
+		op, other = swapCmp(op), e.X
+	}
+
+	x := f.parseOperand(ce.Args[0])
+	if x.Type != expr.Field {
+		return expr.Expr{}, false
+	}
+
+	d := f.tmplValue(f.parseOperand(other), other)
+
+	helper := "timeAdd"
+
+	if f.timeFunc(ce) == "Since" {
+		helper = "timeSub"
+		op = swapCmp(op)
+	}
+
+	bound := expr.NewPipeline(helper + " (now) " + d)
+
+	switch op {
+	case token.LSS:
+		return expr.NewExpr(expr.Lt, x, bound), true
+	case token.LEQ:
+		return expr.NewExpr(expr.Lte, x, bound), true
+	case token.GTR:
+		return expr.NewExpr(expr.Gt, x, bound), true
+	case token.GEQ:
+		return expr.NewExpr(expr.Gte, x, bound), true
+	}
+
+	errorf(f.pi, e, "only <, <=, > and >= comparisons of the duration are supported")
+
+	return expr.Expr{}, false
+}
+
+// swapCmp returns the operator for swapped operands of the comparison.
+func swapCmp(op token.Token) token.Token {
+	switch op {
+	case token.LSS:
+		return token.GTR
+	case token.GTR:
+		return token.LSS
+	case token.LEQ:
+		return token.GEQ
+	case token.GEQ:
+		return token.LEQ
+	}
+
+	return op
+}
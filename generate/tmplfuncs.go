@@ -0,0 +1,349 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// The declarations below the marker are copied into the generated files,
+// so as the tests render the templates with the same functions as the client.
+
+// tigris:funcs
+
+// tigrisFuncs are the functions available to the filter and update templates.
+var tigrisFuncs = template.FuncMap{
+	"toJSON": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	},
+	"toJSONList": func(v any) (string, error) {
+		if rv := reflect.ValueOf(v); !rv.IsValid() || rv.Kind() == reflect.Slice && rv.IsNil() {
+			return "[]", nil
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	},
+	"hasKey": func(m any, k any) bool {
+		mv := reflect.ValueOf(m)
+		kv := reflect.ValueOf(k)
+		if mv.Kind() != reflect.Map || !kv.IsValid() || !kv.Type().ConvertibleTo(mv.Type().Key()) {
+			return false
+		}
+		return mv.MapIndex(kv.Convert(mv.Type().Key())).IsValid()
+	},
+	"captured": func(data any, name string) (any, error) {
+		if m, ok := data.(map[string]any); ok {
+			if c, ok := m["Captured"].(map[string]any); ok {
+				if v, ok := c[name]; ok {
+					return v, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("value of the captured variable '%v' is not supplied", name)
+	},
+	"isType": func(v any, name string) bool {
+		return v != nil && reflect.TypeOf(v).String() == name
+	},
+	"quoteMeta": regexp.QuoteMeta,
+	"now": func() time.Time {
+		return time.Now().UTC()
+	},
+	"timeAdd": func(t time.Time, d time.Duration) time.Time {
+		return t.Add(d)
+	},
+	"timeSub": func(t time.Time, d time.Duration) time.Time {
+		return t.Add(-d)
+	},
+	"timeAddDate": func(t time.Time, years int, months int, days int) time.Time {
+		return t.AddDate(years, months, days)
+	},
+	"timeCmp": func(x any, y any) (int, error) {
+		tx, err := tigrisTime(x)
+		if err != nil {
+			return 0, err
+		}
+		ty, err := tigrisTime(y)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case tx.Before(ty):
+			return -1, nil
+		case tx.After(ty):
+			return 1, nil
+		}
+		return 0, nil
+	},
+	"isNil": func(v any) bool {
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Invalid:
+			return true
+		case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+			return rv.IsNil()
+		}
+		return false
+	},
+	"deref": func(v any) any {
+		rv := reflect.ValueOf(v)
+		for rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return nil
+			}
+			rv = rv.Elem()
+		}
+		if !rv.IsValid() {
+			return nil
+		}
+		return rv.Interface()
+	},
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"trimSpace":  strings.TrimSpace,
+	"trim":       strings.Trim,
+	"trimPrefix": strings.TrimPrefix,
+	"trimSuffix": strings.TrimSuffix,
+	"replaceAll": strings.ReplaceAll,
+	"itoa":       strconv.Itoa,
+	"float":      tigrisFloat,
+	"int":        tigrisInt,
+	"str":        tigrisString,
+	"add": func(x any, y any) (any, error) {
+		return tigrisArith("+", x, y)
+	},
+	"sub": func(x any, y any) (any, error) {
+		return tigrisArith("-", x, y)
+	},
+	"mul": func(x any, y any) (any, error) {
+		return tigrisArith("*", x, y)
+	},
+	"div": func(x any, y any) (any, error) {
+		return tigrisArith("/", x, y)
+	},
+	"mod": func(x any, y any) (any, error) {
+		return tigrisArith("%", x, y)
+	},
+}
+
+// tigrisTime returns the time argument or the time constant,
+// which is marshaled to the template as RFC 3339 string.
+func tigrisTime(v any) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case *time.Time:
+		if t != nil {
+			return *t, nil
+		}
+	case string:
+		return time.Parse(time.RFC3339Nano, t)
+	}
+	return time.Time{}, fmt.Errorf("time expected, got %v", v)
+}
+
+// tigrisFloat converts the numeric argument to float64,
+// the way the integer to float conversion in the filter does.
+func tigrisFloat(v any) (float64, error) {
+	rv := reflect.ValueOf(v)
+
+	switch {
+	case rv.CanInt():
+		return float64(rv.Int()), nil
+	case rv.CanUint():
+		return float64(rv.Uint()), nil
+	case rv.CanFloat():
+		return rv.Float(), nil
+	}
+
+	return 0, fmt.Errorf("can't convert %v to float", v)
+}
+
+// tigrisInt converts the integer argument to int,
+// the way the conversion in the filter does.
+func tigrisInt(v any) (int, error) {
+	rv := reflect.ValueOf(v)
+
+	switch {
+	case rv.CanInt():
+		return int(rv.Int()), nil
+	case rv.CanUint():
+		return int(rv.Uint()), nil
+	}
+
+	return 0, fmt.Errorf("can't convert %v to int", v)
+}
+
+// tigrisString converts the argument of the string kind,
+// like the named string types, to string.
+func tigrisString(v any) (string, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.String {
+		return "", fmt.Errorf("can't convert %v to string", v)
+	}
+
+	return rv.String(), nil
+}
+
+// tigrisArith evaluates arithmetic operation or string concatenation
+// of the filter and update arguments.
+func tigrisArith(op string, x any, y any) (any, error) {
+	a, b := reflect.ValueOf(x), reflect.ValueOf(y)
+	if !a.IsValid() || !b.IsValid() {
+		return nil, fmt.Errorf("invalid operands of %v: %v, %v", op, x, y)
+	}
+
+	if a.Kind() == reflect.String && b.Kind() == reflect.String && op == "+" {
+		return reflect.ValueOf(a.String() + b.String()).Convert(a.Type()).Interface(), nil
+	}
+
+	isInt := func(v reflect.Value) bool { return v.CanInt() || v.CanUint() }
+	isNum := func(v reflect.Value) bool { return isInt(v) || v.CanFloat() }
+
+	if !isNum(a) || !isNum(b) {
+		return nil, fmt.Errorf("unsupported operands of %v: %v, %v", op, x, y)
+	}
+
+	// template passes constants as int or float64,
+	// so the result has the type of the other operand,
+	// unless the constant is float and the operand is integer
+	t := a.Type()
+	if t != b.Type() && (t == reflect.TypeOf(0) || t == reflect.TypeOf(0.0)) &&
+		(isInt(a) || !isInt(b)) {
+		t = b.Type()
+	}
+
+	var (
+		res reflect.Value
+		err error
+	)
+
+	switch {
+	case !isInt(a) || !isInt(b):
+		res, err = tigrisArithFloat(op, a, b)
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uintptr:
+		res, err = tigrisArithUint(op, a, b)
+	default:
+		res, err = tigrisArithInt(op, a, b)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !res.IsValid() {
+		return nil, fmt.Errorf("unsupported operation %v on %v, %v", op, x, y)
+	}
+
+	return res.Convert(t).Interface(), nil
+}
+
+func tigrisArithInt(op string, a reflect.Value, b reflect.Value) (reflect.Value, error) {
+	toInt := func(v reflect.Value) int64 {
+		if v.CanUint() {
+			return int64(v.Uint())
+		}
+		return v.Int()
+	}
+
+	i, j := toInt(a), toInt(b)
+	if (op == "/" || op == "%") && j == 0 {
+		return reflect.Value{}, fmt.Errorf("integer divide by zero")
+	}
+
+	switch op {
+	case "+":
+		return reflect.ValueOf(i + j), nil
+	case "-":
+		return reflect.ValueOf(i - j), nil
+	case "*":
+		return reflect.ValueOf(i * j), nil
+	case "/":
+		return reflect.ValueOf(i / j), nil
+	case "%":
+		return reflect.ValueOf(i % j), nil
+	}
+
+	return reflect.Value{}, nil
+}
+
+// tigrisArithUint evaluates the operation on unsigned integers,
+// which don't fit into int64, wrapping around the same way Go does.
+func tigrisArithUint(op string, a reflect.Value, b reflect.Value) (reflect.Value, error) {
+	toUint := func(v reflect.Value) uint64 {
+		if v.CanInt() {
+			return uint64(v.Int())
+		}
+		return v.Uint()
+	}
+
+	i, j := toUint(a), toUint(b)
+	if (op == "/" || op == "%") && j == 0 {
+		return reflect.Value{}, fmt.Errorf("integer divide by zero")
+	}
+
+	switch op {
+	case "+":
+		return reflect.ValueOf(i + j), nil
+	case "-":
+		return reflect.ValueOf(i - j), nil
+	case "*":
+		return reflect.ValueOf(i * j), nil
+	case "/":
+		return reflect.ValueOf(i / j), nil
+	case "%":
+		return reflect.ValueOf(i % j), nil
+	}
+
+	return reflect.Value{}, nil
+}
+
+func tigrisArithFloat(op string, a reflect.Value, b reflect.Value) (reflect.Value, error) {
+	f, err := tigrisFloat(a.Interface())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	g, err := tigrisFloat(b.Interface())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	switch op {
+	case "+":
+		return reflect.ValueOf(f + g), nil
+	case "-":
+		return reflect.ValueOf(f - g), nil
+	case "*":
+		return reflect.ValueOf(f * g), nil
+	case "/":
+		return reflect.ValueOf(f / g), nil
+	}
+
+	return reflect.Value{}, nil
+}
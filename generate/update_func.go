@@ -20,7 +20,6 @@ import (
 	"go/token"
 	"go/types"
 
-	"github.com/rs/zerolog/log"
 	"github.com/tigrisdata/tigrisgen/expr"
 	"github.com/tigrisdata/tigrisgen/marshal/tigris"
 	"github.com/tigrisdata/tigrisgen/util"
@@ -29,8 +28,10 @@ import (
 
 var ErrOnlyClientSideAllowed = fmt.Errorf("only client side evaluated conditions allowed in the update function")
 
-func parseUpdateFunction(name string, fn *ast.FuncDecl, pi *packages.Package) (string, string) {
-	log.Debug().Str("name", name).Msg("parsing update function")
+func (g *generator) parseUpdateFunction(name string, fn *ast.FuncDecl, pi *packages.Package) (_ FilterDef, err error) {
+	defer catch(pi, name, fn, &err)
+
+	g.log.Debug().Str("name", name).Msg("parsing update function")
 
 	if fn.Type.Results != nil {
 		util.Fatal("Update should not return results")
@@ -68,17 +69,18 @@ func parseUpdateFunction(name string, fn *ast.FuncDecl, pi *packages.Package) (s
 	}
 
 	f := funcParser{
-		pi:  pi,
+		generator: g, pi: pi, fn: fn,
 		doc: arg0, docType: arg0type,
 		args: arg1, argsType: arg1type,
+		captured: newCaptures(),
 	}
 
-	log.Debug().Str("param_name", f.doc).Msg("doc")
-	log.Debug().Str("param_name", f.args).Msg("args")
+	f.log.Debug().Str("param_name", f.doc).Msg("doc")
+	f.log.Debug().Str("param_name", f.args).Msg("args")
 
 	upd := f.parseUpdateBlockStmt(fn.Body)
 
-	return name, tigris.MarshalUpdate(upd)
+	return FilterDef{Name: name, Body: tigris.MarshalUpdate(upd), Captured: f.captured.names}, nil
 }
 
 func updOp(op expr.Op, lhs expr.Operand, rhs expr.Operand) expr.Expr {
@@ -90,24 +92,35 @@ func (f *funcParser) parseUpdateBlockStmt(block *ast.BlockStmt) []expr.Expr {
 
 	for _, v := range block.List {
 		switch e := v.(type) {
+		case *ast.DeclStmt:
+			f.parseDeclLocals(e)
 		case *ast.AssignStmt:
-			log.Debug().Msg("Assignment statement")
+			f.log.Debug().Msg("Assignment statement")
+
+			if e.Tok == token.DEFINE {
+				f.parseAssignLocals(e)
+				continue
+			}
 
 			if len(e.Lhs) != 1 {
-				util.Fatal("Only one operand is allowed on left hand side")
+				errorf(f.pi, e, "Only one operand is allowed on left hand side")
 			}
 
 			if len(e.Rhs) != 1 {
-				util.Fatal("Only one operand is allowed on right hand side")
+				errorf(f.pi, e, "Only one operand is allowed on right hand side")
 			}
 
 			lhs := f.parseOperand(e.Lhs[0])
 			if lhs.Type != expr.Field {
-				util.Fatal("Document field is expected on the left hand side")
+				errorf(f.pi, e.Lhs[0], "Document field is expected on the left hand side")
 			}
 
 			switch ee := e.Rhs[0].(type) {
 			case *ast.CallExpr:
+				if _, ok := f.argCallFunc(ee); ok {
+					break
+				}
+
 				fn := f.parseFuncCall(ee)
 				if fn.Type == expr.PushOp {
 					if lhs.Value.(string) == fn.X.Value.(string) {
@@ -119,12 +132,12 @@ func (f *funcParser) parseUpdateBlockStmt(block *ast.BlockStmt) []expr.Expr {
 					continue
 				}
 
-				FatalWithExpr(f.pi, e, "Unsupported update statement")
+				errorf(f.pi, e, "Unsupported update statement")
 			}
 
 			rhs := f.parseOperand(e.Rhs[0])
 			if rhs.Type != expr.Constant && rhs.Type != expr.Arg {
-				util.Fatal("Arguments field is expected on the right hand side")
+				errorf(f.pi, e.Rhs[0], "Arguments field is expected on the right hand side")
 			}
 
 			switch e.Tok {
@@ -143,7 +156,7 @@ func (f *funcParser) parseUpdateBlockStmt(block *ast.BlockStmt) []expr.Expr {
 			case token.DEC: // --
 				upd = append(upd, updOp(expr.DecOp, lhs, expr.NewConstant(1)))
 			default:
-				FatalWithExpr(f.pi, e, "Unsupported assignment operator")
+				errorf(f.pi, e, "Unsupported assignment operator")
 			}
 
 		case *ast.IfStmt:
@@ -151,7 +164,7 @@ func (f *funcParser) parseUpdateBlockStmt(block *ast.BlockStmt) []expr.Expr {
 
 			upd = append(upd, expr.NewUpdIfExpr(expr.UpdIfOp, ifCond, ifBody))
 		default:
-			FatalWithExpr(f.pi, e, "Unsupported update statement")
+			errorf(f.pi, e, "Unsupported update statement")
 		}
 	}
 
@@ -159,10 +172,10 @@ func (f *funcParser) parseUpdateBlockStmt(block *ast.BlockStmt) []expr.Expr {
 }
 
 func (f *funcParser) parseUpdateIfStatement(stmt *ast.IfStmt) (expr.Expr, []expr.Expr) {
-	log.Debug().Msg("parseIfStatement")
+	f.log.Debug().Msg("parseIfStatement")
 
 	if stmt.Init != nil {
-		util.Fatal("if init section is not supported")
+		f.parseIfInit(stmt.Init)
 	}
 
 	var ifCond expr.Expr
@@ -171,15 +184,20 @@ func (f *funcParser) parseUpdateIfStatement(stmt *ast.IfStmt) (expr.Expr, []expr
 	case *ast.BinaryExpr:
 		ifCond = f.parseBinaryExpr(e)
 	case *ast.Ident: // if true/false/bool field/bool arg {}
+		if f.isLocal(e) {
+			ifCond = f.parseBinaryExprLow(e)
+			break
+		}
+
 		x := f.parseOperand(e)
 		if x.Type == expr.Field {
-			FatalWithExpr(f.pi, e, ErrOnlyClientSideAllowed.Error())
+			errorf(f.pi, e, ErrOnlyClientSideAllowed.Error())
 		} else if x.Type == expr.Arg {
 			ifCond = expr.NewExpr(expr.Eq, x, expr.NewConstant(true)).Client()
 		} else if x.Type == expr.Constant {
 			b, ok := x.Value.(bool)
 			if !ok {
-				FatalWithExpr(f.pi, e, "unsupported constant in if")
+				errorf(f.pi, e, "unsupported constant in if")
 			}
 			if b {
 				ifCond = expr.True
@@ -190,16 +208,16 @@ func (f *funcParser) parseUpdateIfStatement(stmt *ast.IfStmt) (expr.Expr, []expr
 	case *ast.SelectorExpr: // if doc.Field {}
 		x := f.parseOperand(e)
 		if x.Type == expr.Field {
-			FatalWithExpr(f.pi, e, ErrOnlyClientSideAllowed.Error())
+			errorf(f.pi, e, ErrOnlyClientSideAllowed.Error())
 		} else if x.Type == expr.Arg {
 			ifCond = expr.NewExpr(expr.Eq, x, expr.NewConstant(true)).Client()
 		} else {
-			FatalWithExpr(f.pi, e, "unsupported selector in if condition")
+			errorf(f.pi, e, "unsupported selector in if condition")
 		}
 	case *ast.UnaryExpr:
 		ifCond = f.parseUnaryNegation(e.X)
 	default:
-		FatalWithExpr(f.pi, e, "unsupported statement if statement")
+		errorf(f.pi, e, "unsupported statement if statement")
 	}
 
 	ifBody := f.parseUpdateBlockStmt(stmt.Body)
@@ -216,7 +234,7 @@ func (f *funcParser) parseUpdateIfStatement(stmt *ast.IfStmt) (expr.Expr, []expr
 		elseBody := f.parseUpdateBlockStmt(e)
 		return expr.Negate(ifCond), elseBody
 	default:
-		FatalWithExpr(f.pi, e, "unknown else statement")
+		errorf(f.pi, e, "unknown else statement")
 	}
 
 	return expr.Expr{}, nil
@@ -15,6 +15,7 @@
 package generate
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -115,6 +116,63 @@ func parseUpdateFunc_simple_arg(d *Doc, arg float64) {
 	d.FieldFloat = arg
 }
 
+// Update:
+//
+//	{"$set":
+//		{ {{ if eq (isNil .Arg.ArgPtrStr) false }}
+//			"field_string":{{toJSON (deref .Arg.ArgPtrStr)}}
+//		{{end}}
+//		{{ if eq (isNil .Arg.ArgPtrStr) false }},{{end}}
+//		"field_int":1
+//	}}
+func parseUpdateFunc_optional_arg(d *Doc, args Args) {
+	if args.ArgPtrStr != nil {
+		d.FieldString = *args.ArgPtrStr
+	}
+
+	d.FieldInt = 1
+}
+
+// Update:
+//
+//	{"$set":{"field_string":{{toJSON (replaceAll .Arg.ArgString " " "_")}},"field_time":{{toJSON (.Arg.ArgTime.Truncate 3600000000000)}}}}
+func parseUpdateFunc_arg_call(d *Doc, args Args) {
+	d.FieldString = strings.ReplaceAll(args.ArgString, " ", "_")
+	d.FieldTime = args.ArgTime.Truncate(time.Hour)
+}
+
+// Update:
+//
+//	{"$set":{"field_int":{{toJSON (mul .Arg.ArgInt 10)}},"field_string":{{toJSON (add (add .Arg.ArgString "-") .Arg.NestedArg.ArgString)}}},"$increment":{"field_float":{{toJSON (div .Arg.ArgFloat 2)}}}}
+func parseUpdateFunc_arith_args(d *Doc, args Args) {
+	d.FieldInt = args.ArgInt * 10
+	d.FieldString = args.ArgString + "-" + args.NestedArg.ArgString
+	d.FieldFloat += args.ArgFloat / 2
+}
+
+// Update:
+//
+//	{"$set":{"field_int":{{toJSON .Arg.ArgInt}},"field_string":"abc"},"$increment":{"nested.field_float":{{toJSON .Arg.NestedArg.ArgFloat}}}}
+func parseUpdateFunc_local_vars(d *Doc, args Args) {
+	v := args.ArgInt
+	nested := args.NestedArg
+
+	var s = "abc"
+
+	d.FieldInt = v
+	d.FieldString = s
+	d.Nested.FieldFloat += nested.ArgFloat
+}
+
+// Update:
+//
+//	{"$increment":{ {{ if eq (hasKey .Arg.ArgMap "inc") true }}"field_int":{{toJSON .Arg.ArgMap.inc}}{{end}}}}
+func parseUpdateFunc_if_init(d *Doc, args Args) {
+	if v, ok := args.ArgMap["inc"]; ok {
+		d.FieldInt += v
+	}
+}
+
 func TestUpdateFunc(t *testing.T) {
 	execTests(t, "parseUpdateFunc_", true)
 }
@@ -0,0 +1,153 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// findWrappers detects the functions of the main module which forward
+// their function parameters to the API functions. Such wrappers are added
+// to the API catalog, so as their call sites are treated as API calls.
+// Wrappers of wrappers are detected by repeating the pass until
+// no new wrappers found.
+func (g *generator) findWrappers(pkgs []*packages.Package) {
+	var scan []*packages.Package
+
+	packages.Visit(pkgs, func(p *packages.Package) bool {
+		if p.Module != nil && p.Module.Main && p.TypesInfo != nil && !g.scanned[p] {
+			g.scanned[p] = true
+			scan = append(scan, p)
+		}
+
+		return true
+	}, nil)
+
+	for changed := true; changed; {
+		changed = false
+
+		for _, pi := range scan {
+			for _, f := range pi.Syntax {
+				for _, d := range f.Decls {
+					if fd, ok := d.(*ast.FuncDecl); ok && fd.Body != nil && g.findForwards(pi, fd) {
+						changed = true
+					}
+				}
+			}
+		}
+	}
+}
+
+// funcParams returns positions of the function parameters, as they
+// appear in the call arguments.
+func funcParams(pi *packages.Package, fd *ast.FuncDecl) map[*types.Var]int {
+	params := make(map[*types.Var]int)
+
+	i := 0
+
+	for _, p := range fd.Type.Params.List {
+		if len(p.Names) == 0 {
+			i++
+			continue
+		}
+
+		for _, name := range p.Names {
+			if v, ok := pi.TypesInfo.Defs[name].(*types.Var); ok {
+				params[v] = i
+			}
+
+			i++
+		}
+	}
+
+	return params
+}
+
+// findForwards registers the function as API wrapper if it passes its
+// parameters as filter or update arguments to the API functions.
+// Returns true if new filter or update position is added to the catalog.
+func (g *generator) findForwards(pi *packages.Package, fd *ast.FuncDecl) bool {
+	obj, ok := pi.TypesInfo.Defs[fd.Name].(*types.Func)
+	if !ok {
+		return false
+	}
+
+	params := funcParams(pi, fd)
+	if len(params) == 0 {
+		return false
+	}
+
+	path, name := funcKey(obj)
+
+	var changed bool
+
+	forward := func(ce *ast.CallExpr, pos []int, update bool) {
+		for _, p := range pos {
+			if p >= len(ce.Args) {
+				continue
+			}
+
+			id, ok := unparen(ce.Args[p]).(*ast.Ident)
+			if !ok {
+				continue
+			}
+
+			v, ok := pi.TypesInfo.Uses[id].(*types.Var)
+			if !ok {
+				continue
+			}
+
+			i, ok := params[v]
+			if !ok {
+				continue
+			}
+
+			g.forwarded[ce.Args[p]] = true
+
+			if g.apis.addPosition(path, name, i, update) {
+				g.log.Debug().Str("wrapper", path+"."+name).Int("position", i).Bool("update", update).
+					Msg("detected API wrapper")
+
+				changed = true
+			}
+		}
+	}
+
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		ce, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+
+		fns, _ := g.resolveCallee(pi, ce.Fun, make(map[*types.Var]bool))
+
+		for _, f := range fns {
+			api := g.apis.lookup(funcKey(f))
+			if api == nil {
+				continue
+			}
+
+			forward(ce, api.Filters, false)
+			forward(ce, api.Updates, true)
+		}
+
+		return true
+	})
+
+	return changed
+}
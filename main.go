@@ -15,9 +15,15 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/tigrisdata/tigrisgen/generate"
 )
 
 func main() {
-	generate.MainLow()
+	if err := generate.MainLow(); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
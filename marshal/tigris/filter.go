@@ -17,40 +17,41 @@ package tigris
 import (
 	"bytes"
 	"encoding/json"
+	"regexp"
 
 	"github.com/tigrisdata/tigrisgen/expr"
 	"github.com/tigrisdata/tigrisgen/util"
 )
 
-func marshalTmplCondLow(flt expr.Expr, buf *bytes.Buffer) {
-	b := util.Must(json.Marshal(flt.Y.Value))
+// tmplOperand returns the template value of the argument or constant.
+// Float constants are written with the fraction, so as the template
+// compares them with the float arguments.
+func tmplOperand(op expr.Operand) string {
+	if op.Type == expr.Arg {
+		return op.ArgPath()
+	}
 
-	buf.WriteString(string(expr.TemplOps[flt.Type]))
-	buf.WriteString(" .Arg")
+	b := util.Must(json.Marshal(op.Value))
 
-	if flt.X.Value.(string) != "" {
-		buf.WriteString(".")
-		buf.WriteString(flt.X.Value.(string))
+	if _, ok := op.Value.(float64); ok && !bytes.ContainsAny(b, ".eE") {
+		b = append(b, ".0"...)
 	}
 
-	buf.WriteString(" ")
-	buf.Write(b)
+	return string(b)
 }
 
-func marshalTmplCond(flt expr.Expr, buf *bytes.Buffer) {
-	b := util.Must(json.Marshal(flt.Y.Value))
-
-	buf.WriteString("{{ if ")
+func marshalTmplCondLow(flt expr.Expr, buf *bytes.Buffer) {
 	buf.WriteString(string(expr.TemplOps[flt.Type]))
-	buf.WriteString(" .Arg")
-
-	if flt.X.Value.(string) != "" {
-		buf.WriteString(".")
-		buf.WriteString(flt.X.Value.(string))
-	}
+	buf.WriteString(" ")
+	buf.WriteString(tmplOperand(flt.X))
 
 	buf.WriteString(" ")
-	buf.Write(b)
+	buf.WriteString(tmplOperand(flt.Y))
+}
+
+func marshalTmplCond(flt expr.Expr, buf *bytes.Buffer) {
+	buf.WriteString("{{ if ")
+	marshalTmplExpr(flt, buf)
 	buf.WriteString(" }}")
 }
 
@@ -67,7 +68,7 @@ func marshalListTmplCond(flt expr.Expr, buf *bytes.Buffer) {
 			for _, vv := range v.ListClient {
 				buf.WriteString(" ")
 				buf.WriteString("( ")
-				marshalTmplCondLow(vv, buf)
+				marshalTmplExpr(vv, buf)
 				buf.WriteString(" )")
 			}
 
@@ -83,8 +84,87 @@ func marshalListTmplCond(flt expr.Expr, buf *bytes.Buffer) {
 	buf.WriteString(" }}")
 }
 
+// marshalPattern converts prefix and suffix matches to the regex
+// operators, escaping the pattern.
+func marshalPattern(flt expr.Expr) (expr.Op, []byte) {
+	var (
+		op           = expr.Regex
+		begin, end   string
+		qBegin, qEnd string
+	)
+
+	switch flt.Type {
+	case expr.NotPrefix, expr.NotSuffix:
+		op = expr.NotRegex
+	case expr.Regex, expr.NotRegex:
+		if flt.Y.Type == expr.Arg {
+			return flt.Type, []byte("{{toJSON " + flt.Y.ArgPath() + "}}")
+		}
+
+		return flt.Type, util.Must(json.Marshal(flt.Y.Value))
+	}
+
+	if flt.Type == expr.Prefix || flt.Type == expr.NotPrefix {
+		begin, qBegin = "^", `"^" `
+	} else {
+		end, qEnd = "$", ` "$"`
+	}
+
+	if flt.Y.Type == expr.Arg {
+		return op, []byte("{{toJSON (print " + qBegin + "(quoteMeta " + flt.Y.ArgPath() + ")" + qEnd + ")}}")
+	}
+
+	s, ok := flt.Y.Value.(string)
+	if !ok {
+		util.Fatal("string pattern expected")
+	}
+
+	return op, util.Must(json.Marshal(begin + regexp.QuoteMeta(s) + end))
+}
+
 func marshalCond(flt expr.Expr, buf *bytes.Buffer) {
 	n := util.Must(json.Marshal(flt.X.Value))
+
+	switch flt.Type {
+	case expr.Regex, expr.NotRegex, expr.Prefix, expr.NotPrefix, expr.Suffix, expr.NotSuffix:
+		op, p := marshalPattern(flt)
+
+		buf.WriteString(`{`)
+		buf.Write(n)
+		buf.WriteString(`:{"` + string(op) + `":`)
+		buf.Write(p)
+		buf.WriteString(`}}`)
+
+		return
+	case expr.Exists, expr.NotExists:
+		buf.WriteString(`{`)
+		buf.Write(n)
+		buf.WriteString(`:{"` + string(expr.Exists) + `":`)
+
+		if flt.Type == expr.Exists {
+			buf.WriteString(`true}}`)
+		} else {
+			buf.WriteString(`false}}`)
+		}
+
+		return
+	case expr.In, expr.Nin:
+		buf.WriteString(`{`)
+		buf.Write(n)
+		buf.WriteString(`:{"` + string(flt.Type) + `":`)
+
+		if flt.Y.Type == expr.Arg {
+			// nil slice is marshaled as empty list
+			buf.WriteString("{{toJSONList " + flt.Y.ArgPath() + "}}")
+		} else {
+			buf.Write(util.Must(json.Marshal(flt.Y.Value)))
+		}
+
+		buf.WriteString(`}}`)
+
+		return
+	}
+
 	v := util.Must(json.Marshal(flt.Y.Value))
 
 	buf.WriteString(`{`)
@@ -93,14 +173,7 @@ func marshalCond(flt expr.Expr, buf *bytes.Buffer) {
 
 	if flt.Type == expr.Eq {
 		if flt.Y.Type == expr.Arg {
-			buf.WriteString("{{toJSON .Arg")
-
-			if flt.Y.Value.(string) != "" {
-				buf.WriteString(".")
-				buf.WriteString(flt.Y.Value.(string))
-			}
-
-			buf.WriteString("}}")
+			buf.WriteString("{{toJSON " + flt.Y.ArgPath() + "}}")
 		} else {
 			buf.Write(v)
 		}
@@ -109,12 +182,7 @@ func marshalCond(flt expr.Expr, buf *bytes.Buffer) {
 		buf.WriteString(string(flt.Type))
 		buf.WriteString(`":`)
 		if flt.Y.Type == expr.Arg {
-			buf.WriteString("{{toJSON .Arg")
-			if flt.Y.Value.(string) != "" {
-				buf.WriteString(".")
-				buf.WriteString(flt.Y.Value.(string))
-			}
-			buf.WriteString("}}")
+			buf.WriteString("{{toJSON " + flt.Y.ArgPath() + "}}")
 		} else {
 			buf.Write(v)
 		}
@@ -124,55 +192,181 @@ func marshalCond(flt expr.Expr, buf *bytes.Buffer) {
 	buf.WriteString(`}`)
 }
 
+// isOptional returns true for the condition guarded by the client side
+// condition, which is omitted from the array when the guard is false.
+func isOptional(flt expr.Expr) bool {
+	return flt.Type == expr.AndOp && len(flt.ListClient) > 0
+}
+
+// guardCond returns the client side condition guarding the optional condition.
+func guardCond(flt expr.Expr) expr.Expr {
+	if len(flt.ListClient) == 1 {
+		return flt.ListClient[0]
+	}
+
+	return expr.Expr{Type: expr.AndOp, ListClient: flt.ListClient}
+}
+
+// renderGuard returns the client side condition, which should be true
+// for the condition to be rendered. The OR condition, which consists of
+// the optional conditions only, is empty when all their guards are false,
+// so it doesn't match any document. Such condition is omitted from the
+// enclosing OR, otherwise the whole filter is rendered empty.
+func renderGuard(flt expr.Expr) (expr.Expr, bool) {
+	var guards []expr.Expr
+
+	switch flt.Type {
+	case expr.AndOp:
+		guards = append(guards, flt.ListClient...)
+
+		for _, v := range flt.List {
+			if g, ok := renderGuard(v); ok {
+				guards = append(guards, g)
+			}
+		}
+	case expr.OrOp:
+		if len(flt.List) == 0 {
+			return expr.Expr{}, false
+		}
+
+		for _, v := range flt.List {
+			g, ok := renderGuard(v)
+			if !ok {
+				return expr.Expr{}, false
+			}
+
+			guards = append(guards, g)
+		}
+
+		guards = append(guards, flt.ListClient...)
+	case expr.ElemMatch:
+		return renderGuard(flt.List[0])
+	}
+
+	if len(guards) == 0 {
+		return expr.Expr{}, false
+	}
+
+	if len(guards) == 1 {
+		return guards[0], true
+	}
+
+	return expr.Expr{Type: flt.Type, ListClient: guards}, true
+}
+
+// marshalArray marshals the list of the conditions. Elements before the last
+// non-optional element are followed by the comma, elements after it are
+// preceded by the comma. If all the elements are optional, the comma is put
+// before the element only if any of the preceding elements is rendered.
+// Elements of the OR, which may be not rendered, are optional as well.
 func marshalArray(flt expr.Expr, buf *bytes.Buffer) {
 	buf.WriteString(`{"` + string(flt.Type) + `":[`)
 
-	nOptional := 0
+	guardOf := func(v expr.Expr) (expr.Expr, bool) {
+		if flt.Type == expr.OrOp {
+			return renderGuard(v)
+		}
 
-	for _, vv := range flt.List {
-		if len(vv.ListClient) > 0 {
-			nOptional++
+		if isOptional(v) {
+			return guardCond(v), true
 		}
+
+		return expr.Expr{}, false
 	}
 
-	oneNonOpt := (len(flt.List) - nOptional) == 1
+	last := -1
+
+	for i, vv := range flt.List {
+		if _, ok := guardOf(vv); !ok {
+			last = i
+		}
+	}
 
-	preComma := false
+	var guards []expr.Expr
 
 	for i, vv := range flt.List {
-		comma := i < len(flt.List)-1 && (!oneNonOpt || len(vv.ListClient) != 0)
-		marshalFilterLow(vv, buf, preComma, comma)
-		preComma = oneNonOpt && len(vv.ListClient) == 0
+		var pre, post string
+
+		g, optional := guardOf(vv)
+
+		switch {
+		case last < 0:
+			if len(guards) > 0 {
+				var condBuf bytes.Buffer
+
+				marshalCommaCond(guards, &condBuf)
+				condBuf.WriteString(",{{end}}")
+
+				pre = condBuf.String()
+			}
+
+			guards = append(guards, g)
+		case i < last:
+			post = ","
+		case i > last:
+			pre = ","
+		}
+
+		// the optional condition puts the separators inside its guard,
+		// other conditions, which may be not rendered, are guarded here
+		wrap := optional && !isOptional(vv)
+		if isOptional(vv) && flt.Type == expr.OrOp {
+			_, wrap = renderGuard(expr.Expr{Type: expr.AndOp, List: vv.List})
+		}
+
+		if wrap {
+			marshalTmplCond(g, buf)
+		}
+
+		marshalFilterLow(vv, buf, pre, post)
+
+		if wrap {
+			buf.WriteString("{{end}}")
+		}
 	}
 
 	buf.WriteString("]}")
 }
 
-func putComma(need bool, buf *bytes.Buffer) {
-	if need {
-		buf.WriteString(",")
+// marshalClientOr marshals the OR condition with the client side evaluated
+// conditions. If any of them is true, the whole condition is true and
+// matches all the documents, otherwise the rest of the condition is marshaled:
+//
+//	{{ if <client conditions> }}{}{{else}}<server conditions>{{end}}
+func marshalClientOr(flt expr.Expr, buf *bytes.Buffer) {
+	marshalTmplCond(expr.Expr{Type: expr.OrOp, ListClient: flt.ListClient}, buf)
+
+	buf.WriteString("{}{{else}}")
+
+	if len(flt.List) == 1 && !isOptional(flt.List[0]) {
+		marshalFilterLow(flt.List[0], buf, "", "")
+	} else {
+		marshalArray(expr.Expr{Type: expr.OrOp, List: flt.List}, buf)
 	}
+
+	buf.WriteString("{{end}}")
 }
 
-func marshalFilterLow(flt expr.Expr, buf *bytes.Buffer, preComma, comma bool) {
+// marshalFilterLow marshals the condition surrounded by the separators.
+// Separators of the optional condition are put inside its guard.
+func marshalFilterLow(flt expr.Expr, buf *bytes.Buffer, pre, post string) {
 	switch flt.Type {
 	case expr.OrOp:
-		if len(flt.ListClient) > 0 && len(flt.List) > 0 {
-			util.Fatal("Client side evaluated expressions are not allowed in the OR condition\n" +
-				"These are the expressions which doesn't include document fields")
-		}
+		buf.WriteString(pre)
 
-		if len(flt.ListClient) == 1 {
+		if len(flt.ListClient) > 0 && len(flt.List) > 0 {
+			marshalClientOr(flt, buf)
+		} else if len(flt.ListClient) == 1 {
 			marshalTmplCond(flt.ListClient[0], buf)
 		} else if len(flt.ListClient) > 0 {
 			marshalListTmplCond(flt, buf)
 		} else if len(flt.List) == 1 {
-			marshalFilterLow(flt.List[0], buf, false, false)
+			marshalFilterLow(flt.List[0], buf, "", "")
 		} else {
 			marshalArray(flt, buf)
 		}
 
-		putComma(comma, buf)
+		buf.WriteString(post)
 	case expr.AndOp:
 		if len(flt.ListClient) == 1 {
 			marshalTmplCond(flt.ListClient[0], buf)
@@ -180,24 +374,56 @@ func marshalFilterLow(flt expr.Expr, buf *bytes.Buffer, preComma, comma bool) {
 			marshalListTmplCond(flt, buf)
 		}
 
-		putComma(preComma, buf)
+		buf.WriteString(pre)
 
 		if len(flt.List) == 1 {
-			marshalFilterLow(flt.List[0], buf, false, false)
+			marshalFilterLow(flt.List[0], buf, "", "")
 		} else {
 			marshalArray(flt, buf)
 		}
 
-		putComma(comma, buf)
+		buf.WriteString(post)
 
 		if len(flt.ListClient) > 0 {
 			buf.WriteString("{{end}}")
 		}
+	case expr.ElemMatch, expr.NotElemMatch:
+		buf.WriteString(pre)
+		marshalElemMatch(flt, buf)
+		buf.WriteString(post)
 	default:
+		buf.WriteString(pre)
 		marshalCond(flt, buf)
+		buf.WriteString(post)
+	}
+}
+
+// marshalElemMatch marshals the element match condition,
+// negated condition matches arrays without such elements.
+func marshalElemMatch(flt expr.Expr, buf *bytes.Buffer) {
+	buf.WriteString(`{`)
+	buf.Write(util.Must(json.Marshal(flt.X.Value)))
+	buf.WriteString(`:`)
 
-		putComma(comma, buf)
+	if flt.Type == expr.NotElemMatch {
+		buf.WriteString(`{"$not":`)
 	}
+
+	buf.WriteString(`{"` + string(expr.ElemMatch) + `":`)
+
+	if expr.IsTrue(flt.List[0]) {
+		buf.WriteString(`{}`)
+	} else {
+		marshalFilterLow(flt.List[0], buf, "", "")
+	}
+
+	buf.WriteString(`}`)
+
+	if flt.Type == expr.NotElemMatch {
+		buf.WriteString(`}`)
+	}
+
+	buf.WriteString(`}`)
 }
 
 func MarshalFilter(flt expr.Expr) string {
@@ -209,7 +435,23 @@ func MarshalFilter(flt expr.Expr) string {
 		util.Fatal("filter always evaluates to false")
 	}
 
-	marshalFilterLow(flt, &buf, false, false)
+	// the filter, which doesn't match any document with such arguments,
+	// is rendered empty, so as the client can skip the request.
+	// The AND with the client side conditions is guarded by them already.
+	g, wrap := renderGuard(flt)
+	if isOptional(flt) {
+		g, wrap = renderGuard(expr.Expr{Type: expr.AndOp, List: flt.List})
+	}
+
+	if wrap {
+		marshalTmplCond(g, &buf)
+	}
+
+	marshalFilterLow(flt, &buf, "", "")
+
+	if wrap {
+		buf.WriteString("{{end}}")
+	}
 
 	return buf.String()
 }
@@ -15,9 +15,13 @@
 package tigris
 
 import (
+	"bytes"
+	"encoding/json"
 	"testing"
+	"text/template"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/tigrisdata/tigrisgen/expr"
 )
 
@@ -56,6 +60,44 @@ func TestMarshalFilter(t *testing.T) {
 				expr.NewExpr(expr.Eq, expr.NewField("and_field2"), expr.NewConstant("and_value2")),
 			),
 		), exp: `{"$or":[{"field1":"value1"},{"field2":"value2"},{"$and":[{"and_field1":"and_value1"},{"and_field2":"and_value2"}]}]}`},
+		{name: "prefix", flt: expr.NewExpr(expr.Prefix,
+			expr.NewField("field1"),
+			expr.NewConstant(`a.b*"c`),
+		), exp: `{"field1":{"$regex":"^a\\.b\\*\"c"}}`},
+		{name: "not_suffix_arg", flt: expr.Negate(expr.NewExpr(expr.Suffix,
+			expr.NewField("field1"),
+			expr.NewArg("Domain"),
+		)), exp: `{"field1":{"$not_regex":{{toJSON (print (quoteMeta .Arg.Domain) "$")}}}}`},
+		{name: "in_empty", flt: expr.NewExpr(expr.In,
+			expr.NewField("field1"),
+			expr.NewConstant([]any{}),
+		), exp: `{"field1":{"$in":[]}}`},
+		{name: "nin_arg", flt: expr.Negate(expr.NewExpr(expr.In,
+			expr.NewField("field1"),
+			expr.NewArg("IDs"),
+		)), exp: `{"field1":{"$nin":{{toJSONList .Arg.IDs}}}}`},
+		{name: "not_exists", flt: expr.Negate(expr.NewExpr(expr.Exists,
+			expr.NewField("field1.key"),
+			expr.NewConstant(true),
+		)), exp: `{"field1.key":{"$exists":false}}`},
+		{name: "not_null", flt: expr.Negate(expr.NewExpr(expr.Eq,
+			expr.NewField("field1"),
+			expr.NewConstant(nil),
+		)), exp: `{"field1":{"$ne":null}}`},
+		{name: "regex", flt: expr.NewExpr(expr.Regex,
+			expr.NewField("field1"),
+			expr.NewConstant(`^[a-z]+\.com$`),
+		), exp: `{"field1":{"$regex":"^[a-z]+\\.com$"}}`},
+		{name: "or_client_args", flt: expr.Or(
+			expr.NewExpr(expr.Gt, expr.NewArg("From"), expr.NewArg("To")).Client(),
+			expr.NewExpr(expr.Lt, expr.NewConstant(10), expr.NewArg("Limit")).Client(),
+			expr.NewExpr(expr.Eq, expr.NewField("field1"), expr.NewArg("From")),
+		), exp: `{{ if or ( gt .Arg.From .Arg.To ) ( lt 10 .Arg.Limit ) }}{}{{else}}{"field1":{{toJSON .Arg.From}}}{{end}}`},
+		{name: "or_client_float", flt: expr.Or(
+			expr.NewExpr(expr.Eq, expr.NewArg("Ratio"), expr.NewConstant(2.0)).Client(),
+			expr.NewExpr(expr.Lt, expr.NewArg("Ratio"), expr.NewConstant(0.5)).Client(),
+			expr.NewExpr(expr.Eq, expr.NewField("field1"), expr.NewConstant(2.0)),
+		), exp: `{{ if or ( eq .Arg.Ratio 2.0 ) ( lt .Arg.Ratio 0.5 ) }}{}{{else}}{"field1":2}{{end}}`},
 	}
 
 	for _, c := range cases {
@@ -66,6 +108,92 @@ func TestMarshalFilter(t *testing.T) {
 	}
 }
 
+func TestMarshalFilterClientBranches(t *testing.T) {
+	client := func(name string) expr.Expr {
+		return expr.NewExpr(expr.Eq, expr.NewArg(name), expr.NewConstant(true)).Client()
+	}
+
+	field := func(name string) expr.Expr {
+		return expr.NewExpr(expr.Eq, expr.NewField(name), expr.NewConstant(1))
+	}
+
+	// none is the number of the argument combinations,
+	// which don't match any document
+	cases := []struct {
+		name string
+		flt  expr.Expr
+		none int
+	}{
+		{name: "or", flt: expr.Or(client("A"), field("f1"))},
+		{name: "or_many", flt: expr.Or(client("A"), client("B"), field("f1"), field("f2"))},
+		{name: "and_or", flt: expr.And(field("f1"), expr.Or(client("A"), field("f2")), expr.Or(client("B"), field("f3")))},
+		{name: "or_and_guarded", flt: expr.Or(client("A"), expr.And(client("B"), field("f1"))), none: 2},
+		{name: "or_nested", flt: expr.Or(client("A"), expr.And(field("f1"), expr.Or(client("B"), field("f2"))))},
+		{name: "guarded_all", flt: expr.Or(
+			expr.And(client("A"), field("f1")),
+			expr.And(client("B"), field("f2")),
+			expr.And(client("C"), field("f3")),
+		), none: 1},
+		{name: "guarded_first", flt: expr.Or(
+			expr.And(client("A"), field("f1")),
+			field("f2"),
+			field("f3"),
+		)},
+		{name: "guarded_last", flt: expr.Or(
+			field("f1"),
+			field("f2"),
+			expr.And(client("A"), field("f3")),
+		)},
+		{name: "guarded_or", flt: expr.Or(
+			expr.And(client("A"), field("f1")),
+			expr.And(client("B"), expr.Or(client("C"), field("f2"))),
+		), none: 2},
+		{name: "guarded_and", flt: expr.And(client("A"), field("f1"), field("f2")), none: 4},
+		{name: "guarded_nested", flt: expr.Or(
+			field("f1"),
+			expr.And(field("f2"), expr.Or(
+				expr.And(client("A"), field("f3")),
+				expr.And(client("B"), field("f4")),
+			)),
+		)},
+		{name: "guarded_nested_all", flt: expr.Or(
+			expr.And(client("C"), field("f1")),
+			expr.And(field("f2"), expr.Or(
+				expr.And(client("A"), field("f3")),
+				expr.And(client("B"), field("f4")),
+			)),
+		), none: 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tmpl := template.Must(template.New(c.name).Parse(MarshalFilter(c.flt)))
+
+			none := 0
+
+			for i := 0; i < 8; i++ {
+				args := map[string]bool{"A": i&1 != 0, "B": i&2 != 0, "C": i&4 != 0}
+
+				var buf bytes.Buffer
+
+				err := tmpl.Execute(&buf, map[string]any{"Arg": args})
+				require.NoError(t, err)
+
+				// empty filter doesn't match any document
+				if buf.Len() == 0 {
+					none++
+					continue
+				}
+
+				assert.True(t, json.Valid(buf.Bytes()), "%v: %s", args, buf.String())
+				assert.NotContains(t, buf.String(), "[]", "%v", args)
+			}
+
+			assert.Equal(t, c.none, none)
+		})
+	}
+}
+
 func TestMarshalUpdate(t *testing.T) {
 	cases := []struct {
 		name string
@@ -101,3 +229,25 @@ func TestMarshalUpdate(t *testing.T) {
 		assert.Equal(t, c.exp, res)
 	}
 }
+
+func TestMarshalUpdateClientTemplate(t *testing.T) {
+	cond := expr.NewExpr(expr.Eq, expr.NewArg("A"), expr.NewConstant(true)).Client()
+
+	upd := []expr.Expr{
+		expr.NewUpdIfExpr(expr.UpdIfOp, cond, []expr.Expr{
+			expr.NewExpr(expr.SetOp, expr.NewField("field1"), expr.NewConstant(10)),
+		}),
+		expr.NewExpr(expr.SetOp, expr.NewField("field2"), expr.NewConstant(20)),
+	}
+
+	tmpl, err := template.New("update").Parse(MarshalUpdate(upd))
+	assert.NoError(t, err)
+
+	for _, a := range []bool{true, false} {
+		var buf bytes.Buffer
+
+		err = tmpl.Execute(&buf, map[string]any{"Arg": map[string]bool{"A": a}})
+		assert.NoError(t, err)
+		assert.True(t, json.Valid(buf.Bytes()), "%v: %s", a, buf.String())
+	}
+}
@@ -36,14 +36,7 @@ func marshalUpdateExpr(upd expr.Expr, buf *bytes.Buffer) {
 			return
 		}
 
-		buf.WriteString("{{toJSON .Arg")
-
-		if upd.Y.Value.(string) != "" {
-			buf.WriteString(".")
-			buf.WriteString(upd.Y.Value.(string))
-		}
-
-		buf.WriteString("}}")
+		buf.WriteString("{{toJSON " + upd.Y.ArgPath() + "}}")
 	} else {
 		buf.Write(v)
 	}
@@ -61,7 +54,7 @@ func marshalTmplExpr(flt expr.Expr, buf *bytes.Buffer) {
 				buf.WriteString(" )")
 			}
 		} else {
-			marshalTmplCondLow(flt.ListClient[0], buf)
+			marshalTmplExpr(flt.ListClient[0], buf)
 		}
 	} else {
 		marshalTmplCondLow(flt, buf)
@@ -156,6 +149,11 @@ func marshalUpdateLow(upd []expr.Expr, buf *bytes.Buffer) {
 			buf.WriteString(string(v))
 			buf.WriteString(`":{`)
 
+			// "{{{" is not a valid template
+			if bytes.HasPrefix(opBuf.Bytes(), []byte("{{")) {
+				buf.WriteString(` `)
+			}
+
 			buf.Write(opBuf.Bytes())
 
 			buf.WriteString(`}`)
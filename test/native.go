@@ -76,6 +76,16 @@ func (d *Doc) UpdateOnePtr(args int) {
 }
 */
 
+// For inlining tests.
+
+func IsSmall(d *Doc, limit int) bool {
+	return d.Field1 < limit
+}
+
+func (d Doc) HasNested() bool {
+	return d.Nested.Field222 != ""
+}
+
 type NativeCollection[T any, P any] struct{}
 
 type Response struct{}
@@ -113,6 +123,13 @@ func ReadWithOptions[T, P any, F any](ctx context.Context, c *NativeCollection[T
 	return &Response{}, nil
 }
 
+// Find is not in the default API list,
+// it's configured in the tests.
+func Find[T, P any, F any](filter func(T, F) bool, args F, c *NativeCollection[T, P],
+) (*Response, error) {
+	return &Response{}, nil
+}
+
 // Delete removes documents from the collection according to the filter.
 func Delete[T, P any, F any](ctx context.Context, c *NativeCollection[T, P], filter func(T, F) bool, args F,
 ) (*Response, error) {
@@ -124,3 +141,8 @@ func DeleteOne[T, P any, F any](ctx context.Context, c *NativeCollection[T, P],
 ) (*Response, error) {
 	return &Response{}, nil
 }
+
+// Match is the method API, it's configured in the tests.
+func (c *NativeCollection[T, P]) Match(filter func(T, P) bool, args P) (*Response, error) {
+	return &Response{}, nil
+}
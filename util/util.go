@@ -16,9 +16,6 @@ package util
 
 import (
 	"fmt"
-	"os"
-
-	"github.com/rs/zerolog/log"
 )
 
 func Must[T any](v T, err error) T {
@@ -29,11 +26,18 @@ func Must[T any](v T, err error) T {
 	return v
 }
 
-// Fatal can be overridden in tests.
-var Fatal = FatalDef
+// Error is raised by Fatal.
+type Error struct {
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
 
-func FatalDef(format string, args ...any) {
-	log.Error().CallerSkipFrame(1).Msgf(format, args...)
-	_, _ = fmt.Fprintf(os.Stderr, format+"\n", args...)
-	os.Exit(1)
+// Fatal aborts processing of the current function.
+// The error is recovered by the generator and reported
+// along with other diagnostics.
+func Fatal(format string, args ...any) {
+	panic(&Error{Msg: fmt.Sprintf(format, args...)})
 }